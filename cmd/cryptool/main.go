@@ -7,6 +7,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/DimaKropachev/cryptool/internal/cli"
 	"github.com/DimaKropachev/cryptool/pkg/logger"
@@ -19,5 +21,12 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Ctrl-C cancels the running command, a second one kills the process
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	cli.Execute(ctx)
 }
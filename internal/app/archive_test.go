@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+)
+
+func TestEncryptDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		filepath.Join("a", "b", "x.txt"): "hello",
+		filepath.Join("a", "y.txt"):      "world",
+		"z.txt":                          "",
+	}
+
+	src := filepath.Join(dir, "src")
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(src, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	password := []byte("correct horse battery staple")
+	encPath := filepath.Join(dir, "src.crpt")
+	err := Encrypt(context.Background(), src, encPath, EncryptOptions{
+		Algorithm:     algorithms.AlgCHACHA20POLY1305,
+		PasswordSlots: [][]byte{password},
+		KDF:           &crypto.KDFParams{KDF: crypto.KDFPBKDF2, Time: 1000},
+	})
+	if err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+
+	outPath := filepath.Join(dir, "out")
+	type Case struct {
+		name      string
+		overwrite Overwrite
+		err       error
+	}
+
+	cases := []Case{
+		{name: "new directory"},
+		{name: "existing directory", err: ErrOutputExists},
+		{name: "skip existing directory", overwrite: OverwriteSkip},
+		{name: "replace existing directory", overwrite: OverwriteForce},
+	}
+
+	for _, c := range cases {
+		err := Decrypt(context.Background(), encPath, outPath, DecryptOptions{Password: password, Overwrite: c.overwrite})
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+
+		for name, content := range files {
+			data, err := os.ReadFile(filepath.Join(outPath, name))
+			if err != nil || string(data) != content {
+				t.Fatalf("[%s] get content of %s: %q (%v), expected content: %q", c.name, name, data, err, content)
+			}
+		}
+		if info, err := os.Stat(filepath.Join(outPath, "empty")); err != nil || !info.IsDir() {
+			t.Fatalf("[%s] empty directory is not restored: %v", c.name, err)
+		}
+
+		if tmp, _ := filepath.Glob(filepath.Join(dir, ".*")); len(tmp) != 0 {
+			t.Fatalf("[%s] temporary output is left: %v", c.name, tmp)
+		}
+	}
+}
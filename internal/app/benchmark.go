@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"runtime"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/DimaKropachev/cryptool/pkg/crypto"
 	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
 	"github.com/DimaKropachev/cryptool/pkg/file"
 	mem "github.com/DimaKropachev/cryptool/pkg/memory"
 	"github.com/DimaKropachev/cryptool/pkg/table"
@@ -102,72 +104,33 @@ func Benchmark(inputPath string) error {
 }
 
 func encrypt(algName string, inputPath string) error {
-	var (
-		salt = crypto.GenerateSalt(16)
-	)
-
-	in, err := os.Stat(inputPath)
-	if err != nil {
-		return fmt.Errorf("error receiving information about an input file: %w", err)
-	}
-
-	alg, id, err := algorithms.CreateAlgorithmByName(algName, nil, salt)
-	if err != nil {
-		return err
-	}
-
-	out, err := os.CreateTemp("", "bench.*.crpt")
+	id, err := algorithms.IDByName(algName)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(out.Name())
-	defer out.Close()
 
-	blockSize, err := CalculateOptimalBlockSize(int(in.Size()))
+	keySize, err := algorithms.KeySize(id)
 	if err != nil {
 		return err
 	}
 
-	header := crypto.NewHeader(id, blockSize, len(salt), alg.GetNonceSize(), salt)
-	encHeader, err := crypto.EncryptHeader(header)
+	in, err := os.Open(inputPath)
 	if err != nil {
-		return fmt.Errorf("error encrypting header: %w", err)
-
-	}
-
-	if _, err := out.Write(encHeader); err != nil {
 		return err
 	}
+	defer in.Close()
 
-	content, errs, err := file.ReadDecryptedFile(inputPath, blockSize)
+	out, err := os.CreateTemp("", "bench.*.crpt")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(out.Name())
+	defer out.Close()
 
-READ:
-	for {
-		select {
-		case plaintext, ok := <-content:
-			if !ok {
-				break READ
-			}
-
-			ciphertext, err := alg.Encrypt(plaintext)
-			if err != nil {
-				return err
-			}
-
-			if _, err = out.Write(ciphertext); err != nil {
-				return err
-			}
-
-		case err, ok := <-errs:
-			if !ok {
-				break READ
-			}
-			return fmt.Errorf("error reading file: %w", err)
-		}
-	}
-
-	return nil
+	// one job measures the algorithm, not the number of cores
+	return cryptool.Encrypt(context.Background(), out, in, cryptool.EncryptOptions{
+		Algorithm: algName,
+		Key:       crypto.GenerateKey(keySize),
+		Jobs:      1,
+	})
 }
@@ -0,0 +1,202 @@
+package app
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/DimaKropachev/cryptool/pkg/config"
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	mem "github.com/DimaKropachev/cryptool/pkg/memory"
+	"github.com/DimaKropachev/cryptool/pkg/table"
+)
+
+const (
+	calibrationKeySize = 32
+
+	// the smallest N scrypt accepts, 2 KiB of memory
+	minScryptMemory = 2
+	// argon2id needs 8 KiB per lane
+	minArgon2Memory = 8
+)
+
+var (
+	calibrationPassword = []byte("cryptool calibration")
+	calibrationSalt     = make([]byte, crypto.DefaultSaltSize)
+)
+
+type CalibrateOptions struct {
+	// Target is the time it should take to derive a key
+	Target time.Duration
+	// MaxMemory is the memory budget of scrypt and argon2id in KiB
+	MaxMemory uint32
+	// Save stores the parameters in the config, DefaultKDF becomes the encrypt default
+	Save       bool
+	DefaultKDF crypto.KDF
+}
+
+// CalibrateKDF measures every supported KDF on this machine and picks the
+// parameters that derive a key in about opts.Target. The parameters stay
+// within crypto.DefaultKDFLimits, so decrypt accepts them.
+func CalibrateKDF(opts CalibrateOptions) error {
+	if opts.Target <= 0 {
+		return fmt.Errorf("target time must be positive")
+	}
+	opts.MaxMemory = min(opts.MaxMemory, crypto.DefaultKDFLimits.Memory)
+
+	calibrations := []func(time.Duration, uint32) (*crypto.KDFParams, error){
+		calibratePBKDF2,
+		calibrateScrypt,
+		calibrateArgon2id,
+	}
+
+	result := make([][]string, len(calibrations))
+	params := make([]*crypto.KDFParams, len(calibrations))
+
+	for i, calibrate := range calibrations {
+		p, err := calibrate(opts.Target, opts.MaxMemory)
+		if err != nil {
+			return err
+		}
+
+		d, err := measureKDF(p)
+		if err != nil {
+			return err
+		}
+
+		memory := "-"
+		if p.Memory != 0 {
+			memory = mem.FormatBytes(float64(p.Memory) * 1024)
+		}
+
+		params[i] = p
+		result[i] = []string{p.String(), d.Round(time.Millisecond).String(), memory}
+	}
+
+	table := table.New()
+	headlines := []string{"KDF", "Time", "Memory usage"}
+	table.SetHeader(headlines)
+	if err := table.SetContent(result); err != nil {
+		return err
+	}
+	if err := table.Render(); err != nil {
+		return err
+	}
+
+	if !opts.Save {
+		return nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range params {
+		cfg.SetKDFParams(p)
+	}
+	cfg.DefaultKDF = opts.DefaultKDF.String()
+
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Parameters saved to %s, default kdf: %s\n", cfg.GetPath(), cfg.DefaultKDF)
+	return nil
+}
+
+func measureKDF(params *crypto.KDFParams) (time.Duration, error) {
+	runtime.GC()
+
+	start := time.Now()
+	if _, err := crypto.DeriveKey(calibrationPassword, calibrationSalt, calibrationKeySize, params); err != nil {
+		return 0, err
+	}
+
+	return time.Since(start), nil
+}
+
+// scale returns how many times d fits in target, at least once.
+func scale(target, d time.Duration) float64 {
+	if d <= 0 {
+		d = time.Microsecond
+	}
+	return max(float64(target)/float64(d), 1)
+}
+
+// maxPasses returns how many passes over memory KiB the default limits allow.
+func maxPasses(memory uint32) float64 {
+	return max(float64(crypto.DefaultKDFLimits.Work/uint64(memory)), 1)
+}
+
+func calibratePBKDF2(target time.Duration, _ uint32) (*crypto.KDFParams, error) {
+	params := &crypto.KDFParams{KDF: crypto.KDFPBKDF2, Time: 1000}
+	limit := crypto.DefaultKDFLimits.Iterations
+
+	for {
+		d, err := measureKDF(params)
+		if err != nil {
+			return nil, err
+		}
+
+		// a short run is mostly timer resolution and setup
+		if d < target/10 && params.Time <= limit/4 {
+			params.Time *= 4
+			continue
+		}
+
+		// PBKDF2 time is linear in the number of iterations
+		iterations := float64(params.Time) * float64(target) / float64(max(d, time.Microsecond))
+		params.Time = max(uint32(min(iterations, float64(limit)))/1000*1000, 1000)
+		return params, nil
+	}
+}
+
+func calibrateScrypt(target time.Duration, maxMemory uint32) (*crypto.KDFParams, error) {
+	params := crypto.DefaultKDFParams(crypto.KDFScrypt)
+
+	// N must be a power of two
+	params.Memory = minScryptMemory
+	for params.Memory*2 <= maxMemory {
+		params.Memory *= 2
+	}
+
+	for {
+		d, err := measureKDF(params)
+		if err != nil {
+			return nil, err
+		}
+
+		if d > target && params.Memory > minScryptMemory {
+			params.Memory /= 2
+			continue
+		}
+
+		// parallelization adds time without adding memory
+		params.Threads = uint8(min(scale(target, d), 255, maxPasses(params.Memory)))
+		return params, nil
+	}
+}
+
+func calibrateArgon2id(target time.Duration, maxMemory uint32) (*crypto.KDFParams, error) {
+	params := crypto.DefaultKDFParams(crypto.KDFArgon2id)
+	params.Threads = uint8(min(runtime.NumCPU(), int(params.Threads)))
+	minMemory := minArgon2Memory * uint32(params.Threads)
+	params.Memory = max(maxMemory, minMemory)
+	params.Time = 1
+
+	for {
+		d, err := measureKDF(params)
+		if err != nil {
+			return nil, err
+		}
+
+		if d > target && params.Memory/2 >= minMemory {
+			params.Memory /= 2
+			continue
+		}
+
+		params.Time = uint32(min(scale(target, d), maxPasses(params.Memory)))
+		return params, nil
+	}
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"context"
+	"io"
+
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
+)
+
+// contextReader fails reads once ctx is done, so a copy loop stops between
+// two reads.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, cryptool.Cancelled(r.ctx)
+	}
+	return r.r.Read(p)
+}
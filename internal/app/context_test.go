@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/internal/testutil"
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
+	"github.com/DimaKropachev/cryptool/pkg/file"
+	"github.com/DimaKropachev/cryptool/pkg/models"
+)
+
+func TestCancelRemovesOutput(t *testing.T) {
+	dir := t.TempDir()
+	key := crypto.GenerateKey(32)
+
+	plainPath := filepath.Join(dir, "plain.txt")
+	if err := os.WriteFile(plainPath, crypto.GenerateKey(64*cryptool.MinBlockSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keyfile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyfile, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	encOpts := EncryptOptions{Algorithm: algorithms.AlgAES256GCM, Key: key, ChunkSize: cryptool.MinBlockSize, Jobs: 4}
+	decOpts := DecryptOptions{Keyfile: keyfile, Jobs: 4}
+
+	encPath := filepath.Join(dir, "plain.txt.crpt")
+	if err := encryptFile(context.Background(), &models.File{Name: "plain.txt", Path: plainPath}, encPath, encOpts); err != nil {
+		t.Fatal(err)
+	}
+
+	treePath := filepath.Join(dir, "tree")
+	if err := os.MkdirAll(filepath.Join(treePath, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(treePath, "sub", "plain.txt"), crypto.GenerateKey(64*cryptool.MinBlockSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+	treeInfo, err := os.Stat(treePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeFiles, err := file.ReadDirectory(treePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(dir, "tree.crpt")
+	if err := encryptDirectory(context.Background(), treeInfo, treeFiles, "tree", archivePath, encOpts); err != nil {
+		t.Fatal(err)
+	}
+
+	type Case struct {
+		name string
+		run  func(ctx context.Context, outPath string) error
+	}
+
+	cases := []Case{
+		{
+			name: "encrypt",
+			run: func(ctx context.Context, outPath string) error {
+				return encryptFile(ctx, &models.File{Name: "plain.txt", Path: plainPath}, outPath, encOpts)
+			},
+		},
+		{
+			name: "decrypt",
+			run: func(ctx context.Context, outPath string) error {
+				return decryptFile(ctx, &models.File{Name: "plain.txt.crpt", Path: encPath}, outPath, decOpts)
+			},
+		},
+		{
+			name: "decrypt range",
+			run: func(ctx context.Context, outPath string) error {
+				return decryptRange(ctx, encPath, outPath, DecryptOptions{Keyfile: keyfile, Range: &ByteRange{End: -1}})
+			},
+		},
+		{
+			name: "encrypt directory",
+			run: func(ctx context.Context, outPath string) error {
+				return encryptDirectory(ctx, treeInfo, treeFiles, "tree", outPath, encOpts)
+			},
+		},
+		{
+			name: "decrypt archive",
+			run: func(ctx context.Context, outPath string) error {
+				return decryptFile(ctx, &models.File{Name: "tree.crpt", Path: archivePath}, outPath, decOpts)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		before := runtime.NumGoroutine()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		outPath := filepath.Join(dir, c.name+".out")
+		err := c.run(ctx, outPath)
+		if !errors.Is(err, ErrCancelled) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, ErrCancelled)
+		}
+
+		if _, err := os.Stat(outPath); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("[%s] partial output is left: %v", c.name, err)
+		}
+		if tmp, _ := filepath.Glob(filepath.Join(dir, ".*.tmp-*")); len(tmp) != 0 {
+			t.Fatalf("[%s] temporary output is left: %v", c.name, tmp)
+		}
+		testutil.CheckGoroutines(t, c.name, before)
+	}
+}
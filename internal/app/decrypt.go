@@ -1,157 +1,216 @@
 package app
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/DimaKropachev/cryptool/pkg/archive"
 	"github.com/DimaKropachev/cryptool/pkg/crypto"
-	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
-	"github.com/DimaKropachev/cryptool/pkg/file"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
 	"github.com/DimaKropachev/cryptool/pkg/models"
 	"github.com/DimaKropachev/cryptool/pkg/progressbar"
 )
 
-func Decrypt(inPath, outPath string, password []byte) error {
+type DecryptOptions struct {
+	Password []byte
+	Keyfile  string
+	// Identities are files with the private keys of recipients
+	Identities []string
+	// Range limits the output to these plaintext bytes
+	Range *ByteRange
+	// Jobs is the number of chunks opened in parallel, GOMAXPROCS if zero
+	Jobs int
+	// Overwrite tells what to do with an existing output file
+	Overwrite Overwrite
+	// Mirror decrypts a directory mirrored by encrypt into the output
+	// directory
+	Mirror bool
+	// KDFLimit multiplies crypto.DefaultKDFLimits, the cost a key derivation
+	// read from the file may have
+	KDFLimit uint32
+}
+
+func (opts DecryptOptions) kdfLimits() crypto.KDFLimits {
+	return crypto.DefaultKDFLimits.Scale(opts.KDFLimit)
+}
+
+// Decrypt decrypts inPath to outPath. When ctx is done the partial output is
+// removed and an error wrapping ErrCancelled is returned.
+func Decrypt(ctx context.Context, inPath, outPath string, opts DecryptOptions) error {
 	inPath = filepath.Clean(inPath)
 	outPath = filepath.Clean(outPath)
 
+	if opts.Range != nil {
+		if inPath == StdStream {
+			return ErrRangeOfStdin
+		}
+		if outPath == "." {
+			outPath = StdStream
+		}
+		err := decryptRange(ctx, inPath, outPath, opts)
+		if skipped(err, opts.Overwrite) {
+			return reportSkipped(outPath)
+		}
+		return err
+	}
+
+	if inPath == StdStream {
+		if opts.Mirror {
+			return ErrMirrorNotDir
+		}
+		if outPath == "." {
+			outPath = StdStream
+		}
+		err := decryptStdin(ctx, os.Stdin, os.Stdout, outPath, opts)
+		if skipped(err, opts.Overwrite) {
+			return reportSkipped(outPath)
+		}
+		return err
+	}
+
 	nodeInfo, err := os.Stat(inPath)
 	if err != nil {
 		return fmt.Errorf("error receiving information about an input data: %w", err)
 	}
 
+	if opts.Mirror {
+		if !nodeInfo.IsDir() {
+			return ErrMirrorNotDir
+		}
+		return decryptMirror(ctx, inPath, outPath, opts)
+	}
+
 	if nodeInfo.IsDir() {
+		return ErrInputIsDir
+	}
 
-	} else {
-		pb := progressbar.New(progressbar.PrefixDecrypt+": "+nodeInfo.Name(), nodeInfo.Size())
+	pb := progressbar.New(progressbar.PrefixDecrypt+": "+nodeInfo.Name(), nodeInfo.Size())
 
-		file := &models.File{
-			Name: nodeInfo.Name(),
-			Info: nodeInfo,
-			Path: inPath,
-			PB:   pb,
-		}
+	file := &models.File{
+		Name: nodeInfo.Name(),
+		Info: nodeInfo,
+		Path: inPath,
+		PB:   pb,
+	}
 
-		err := decryptFile(file, outPath, password)
-		if err != nil {
-			return err
-		}
+	if outPath == "." {
+		outPath = strings.TrimSuffix(file.Name, ".crpt")
 	}
 
-	in, err := os.OpenFile(inPath, os.O_RDONLY, 0644)
-	if err != nil {
-		return err
+	err = decryptFile(ctx, file, outPath, opts)
+	if skipped(err, opts.Overwrite) {
+		return reportSkipped(outPath)
 	}
-	defer in.Close()
+	return err
+}
 
-	header, err := crypto.DecryptHeader(in)
+func decryptFile(ctx context.Context, f *models.File, outPath string, opts DecryptOptions) (err error) {
+	inFile, err := os.OpenFile(f.Path, os.O_RDONLY, 0644)
 	if err != nil {
-		// error decrypting header
-		return err
+		return fmt.Errorf("error opening input file: %w", err)
 	}
+	defer inFile.Close()
 
-	alg, err := algorithms.CreateAlgorithmByID(int(header.AlgID), password, header.Salt)
+	return decryptReader(ctx, inFile, os.Stdout, outPath, opts, f.PB)
+}
+
+// decryptStdin decrypts stdin to outPath, which is stdout for StdStream.
+func decryptStdin(ctx context.Context, stdin io.Reader, stdout io.Writer, outPath string, opts DecryptOptions) error {
+	return decryptReader(ctx, stdin, stdout, outPath, opts, nil)
+}
+
+// decryptReader decrypts r to the file outPath, or restores the directory
+// tree of an archive to outPath. Archives written to stdout stay tar streams.
+func decryptReader(ctx context.Context, r io.Reader, stdout io.Writer, outPath string, opts DecryptOptions, pb *progressbar.ProgressBar) (err error) {
+	header, r, err := peekHeader(r)
 	if err != nil {
 		return err
 	}
 
-	out, err := os.OpenFile(outPath, os.O_CREATE, 0644)
+	content, err := header.ContentType()
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
+	}
+
+	if content == crypto.ContentArchive && outPath != StdStream {
+		return decryptArchive(ctx, r, header, outPath, opts, pb)
 	}
-	defer out.Close()
 
-	content, errs, err := file.ReadEncryptedFile(in, alg.GetNonceSize(), int(header.BlockSize), alg.GetTagSize())
+	// an existing output is reported before the key derivation
+	out, err := openOutputTo(stdout, outPath, opts.Overwrite)
 	if err != nil {
 		return err
 	}
+	defer func() { err = closeOutput(out, err) }()
 
-READ:
-	for {
-		select {
-		case ciphertext, ok := <-content:
-			if !ok {
-				break READ
-			}
-
-			plaintext, err := alg.Decrypt(ciphertext.Buf, ciphertext.Nonce)
-			if err != nil {
-				return err
-			}
-
-			if _, err := out.Write(plaintext); err != nil {
-				return err
-			}
-
-		case err, ok := <-errs:
-			if !ok {
-				break READ
-			}
-			return fmt.Errorf("error reading file: %w", err)
-		}
+	libOpts, err := decryptOptions(header, opts)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return decryptStream(ctx, r, out, libOpts, pb)
 }
 
-func decryptFile(f *models.File, outPath string, password []byte) error {
-	inFile, err := os.OpenFile(f.Path, os.O_RDONLY, 0644)
+// decryptArchive extracts the archive of r into the directory outPath.
+func decryptArchive(ctx context.Context, r io.Reader, header *crypto.Header, outPath string, opts DecryptOptions, pb *progressbar.ProgressBar) (err error) {
+	out, err := openOutputDir(outPath, opts.Overwrite)
 	if err != nil {
-		return fmt.Errorf("error opening input file: %w", err)
+		return err
 	}
-	defer inFile.Close()
+	defer func() { err = closeOutputDir(out, err) }()
 
-	outFile, err := os.OpenFile(outPath, os.O_CREATE, 0644)
+	libOpts, err := decryptOptions(header, opts)
 	if err != nil {
-		return fmt.Errorf("error accessing the output file: %w", err)
+		return err
 	}
-	defer outFile.Close()
 
-	header, err := crypto.DecryptHeader(inFile)
-	if err != nil {
-		return fmt.Errorf("error reading header: %w", err)
+	pr, pw := io.Pipe()
+	extracted := make(chan error, 1)
+	go func() {
+		err := archive.Extract(ctx, pr, out.tmp)
+		pr.CloseWithError(err)
+		extracted <- err
+	}()
+
+	err = decryptStream(ctx, r, pw, libOpts, pb)
+	// unblocks the extraction when decryption stops early
+	pw.CloseWithError(err)
+
+	extractErr := <-extracted
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return cryptool.Cancelled(ctx)
+	// a failed extraction fails the decryption with its own error
+	case extractErr != nil && (err == nil || errors.Is(err, extractErr)):
+		return extractErr
 	}
+	return err
+}
 
-	alg, err := algorithms.CreateAlgorithmByID(int(header.AlgID), password, header.Salt)
-	if err != nil {
-		return fmt.Errorf("error creating algorithm: %w", err)
-	}
+// decryptStream writes the plaintext of r to w. pb may be nil.
+func decryptStream(ctx context.Context, r io.Reader, w io.Writer, opts cryptool.DecryptOptions, pb *progressbar.ProgressBar) error {
+	pb.Start()
+	defer pb.Finish()
 
-	content, errs, err := file.ReadEncryptedFile(inFile, alg.GetNonceSize(), int(header.BlockSize), alg.GetTagSize())
+	opts.Progress = pb.Add
+	return cryptool.Decrypt(ctx, w, r, opts)
+}
+
+// peekHeader reads the header of r. The returned reader starts at the header
+// again, so this works on pipes too. The header is authenticated later, when
+// the file key is known.
+func peekHeader(r io.Reader) (*crypto.Header, io.Reader, error) {
+	var buf bytes.Buffer
+	header, err := crypto.DecryptHeader(io.TeeReader(r, &buf))
 	if err != nil {
-		return fmt.Errorf("error reading input file: %w", err)
-	}
-
-	f.PB.Start()
-READ:
-	for {
-		select {
-		case ciphertext, ok := <-content:
-			if !ok {
-				f.PB.Finish()
-				break READ
-			}
-
-			plaintext, err := alg.Decrypt(ciphertext.Buf, ciphertext.Nonce)
-			if err != nil {
-				f.PB.Finish()
-				return err
-			}
-
-			if _, err := outFile.Write(plaintext); err != nil {
-				f.PB.Finish()
-				return err
-			}
-
-			f.PB.Add(int(header.BlockSize))
-		case err := <-errs:
-			if err != nil {
-				f.PB.Finish()
-				return fmt.Errorf("error reading file: %w", err)
-			}
-		}
+		return nil, nil, fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
 	}
 
-	return nil
+	return header, io.MultiReader(&buf, r), nil
 }
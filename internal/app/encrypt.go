@@ -1,32 +1,156 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
+	"github.com/DimaKropachev/cryptool/pkg/archive"
 	"github.com/DimaKropachev/cryptool/pkg/crypto"
-	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
 	"github.com/DimaKropachev/cryptool/pkg/file"
 	"github.com/DimaKropachev/cryptool/pkg/models"
 	"github.com/DimaKropachev/cryptool/pkg/progressbar"
 )
 
-func Encrypt(algorithm, inPath, outPath string, password []byte) error {
+type EncryptOptions struct {
+	Algorithm string
+	Password  []byte
+	KDF       *crypto.KDFParams
+	// Key is used as the file key when no password is given
+	Key []byte
+	// KeyfileOut receives a generated Key in KeyfileFormat
+	KeyfileOut    string
+	KeyfileFormat string
+	// Recipients and PasswordSlots unlock a random file key wrapped in the header
+	Recipients    []string
+	PasswordSlots [][]byte
+	// Jobs is the number of chunks sealed in parallel, GOMAXPROCS if zero
+	Jobs int
+	// ChunkSize is the plaintext size of a chunk, cryptool.DefaultBlockSize if zero
+	ChunkSize int
+	// Overwrite tells what to do with an existing output file
+	Overwrite Overwrite
+	// Mirror encrypts every file of a directory to its own file in the
+	// output directory instead of packing them into an archive
+	Mirror bool
+
+	content crypto.ContentType
+}
+
+// Encrypt encrypts inPath to outPath. When ctx is done the partial output is
+// removed and an error wrapping ErrCancelled is returned.
+func Encrypt(ctx context.Context, inPath, outPath string, opts EncryptOptions) error {
 	inPath = filepath.Clean(inPath)
 	outPath = filepath.Clean(outPath)
 
+	if opts.KDF == nil {
+		opts.KDF = crypto.DefaultKDFParams(crypto.KDFPBKDF2)
+	}
+
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = cryptool.DefaultBlockSize
+	}
+	if err := cryptool.ValidateBlockSize(opts.ChunkSize); err != nil {
+		return err
+	}
+
+	// the password wraps a random file key like any other password slot
+	if len(opts.Password) != 0 {
+		opts.PasswordSlots = append([][]byte{opts.Password}, opts.PasswordSlots...)
+		opts.Password = nil
+	}
+
+	if len(opts.Recipients) != 0 || len(opts.PasswordSlots) != 0 {
+		if opts.Key != nil || opts.KeyfileOut != "" {
+			return ErrConflictingKeys
+		}
+	}
+
+	// number of outputs written, a generated key file is kept only along
+	// with an output it opens
+	written := 0
+
+	if opts.Key == nil && len(opts.Recipients) == 0 && len(opts.PasswordSlots) == 0 {
+		if opts.KeyfileOut == "" {
+			return ErrNoKey
+		}
+
+		key, err := newRawKey(opts.Algorithm, opts.KeyfileOut, opts.KeyfileFormat)
+		if err != nil {
+			return err
+		}
+		opts.Key = key
+
+		defer func() {
+			if written == 0 {
+				os.Remove(opts.KeyfileOut)
+			}
+		}()
+	}
+
+	if inPath == StdStream {
+		if opts.Mirror {
+			return ErrMirrorNotDir
+		}
+		if outPath == "." {
+			outPath = StdStream
+		}
+		err := encryptStdin(ctx, os.Stdin, os.Stdout, outPath, opts)
+		if skipped(err, opts.Overwrite) {
+			return reportSkipped(outPath)
+		}
+		if err != nil {
+			return err
+		}
+		written++
+		return nil
+	}
+
 	nodeInfo, err := os.Stat(inPath)
 	if err != nil {
 		return fmt.Errorf("error receiving information about an input data: %w", err)
 	}
 
+	name := nodeInfo.Name()
+
+	if opts.Mirror {
+		if !nodeInfo.IsDir() {
+			return ErrMirrorNotDir
+		}
+		written, err = encryptMirror(ctx, inPath, outPath, opts)
+		return err
+	}
+
 	if nodeInfo.IsDir() {
-		_, err := file.ReadDirectory(inPath)
+		// "." and ".." are named after the directory they stand for
+		if name == "." || name == ".." {
+			absPath, err := filepath.Abs(inPath)
+			if err != nil {
+				return err
+			}
+			name = filepath.Base(absPath)
+		}
+
+		files, err := file.ReadDirectory(inPath)
+		if err != nil {
+			return err
+		}
+
+		if outPath == "." {
+			outPath = name + ".crpt"
+		}
+
+		err = encryptDirectory(ctx, nodeInfo, files, name, outPath, opts)
+		if skipped(err, opts.Overwrite) {
+			return reportSkipped(outPath)
+		}
 		if err != nil {
 			return err
 		}
-		// TODO: что дклать если пользователь указал директорию
 	} else {
 		pb := progressbar.New(progressbar.PrefixEncrypt+": "+nodeInfo.Name(), nodeInfo.Size())
 
@@ -37,87 +161,120 @@ func Encrypt(algorithm, inPath, outPath string, password []byte) error {
 			PB:   pb,
 		}
 
-		err := encryptFile(file, outPath, algorithm, password)
+		if outPath == "." {
+			outPath = file.Name + ".crpt"
+		}
+
+		err := encryptFile(ctx, file, outPath, opts)
+		if skipped(err, opts.Overwrite) {
+			return reportSkipped(outPath)
+		}
 		if err != nil {
 			return err
 		}
 	}
 
-	fmt.Fprintf(os.Stdout, "File %s successfully encrypted", nodeInfo.Name())
+	written++
+
+	// stdout may carry the encrypted data
+	if outPath != StdStream {
+		kind := "File"
+		if nodeInfo.IsDir() {
+			kind = "Directory"
+		}
+		fmt.Fprintf(os.Stdout, "%s %s successfully encrypted", kind, name)
+	}
 	return nil
 }
 
-func encryptFile(f *models.File, outPath, algorithm string, password []byte) error {
-	salt := crypto.GenerateSalt(crypto.DefaultSaltSize)
-
-	alg, algID, err := algorithms.CreateAlgorithmByName(algorithm, password, salt)
+// encryptStdin encrypts stdin to outPath, which is stdout for StdStream.
+func encryptStdin(ctx context.Context, stdin io.Reader, stdout io.Writer, outPath string, opts EncryptOptions) (err error) {
+	out, err := openOutputTo(stdout, outPath, opts.Overwrite)
 	if err != nil {
 		return err
 	}
+	defer func() { err = closeOutput(out, err) }()
 
-	blockSize, err := CalculateOptimalBlockSize(int(f.Info.Size()))
+	return encryptStream(ctx, stdin, out, opts, nil)
+}
+
+func encryptFile(ctx context.Context, f *models.File, outPath string, opts EncryptOptions) (err error) {
+	inFile, err := os.Open(f.Path)
 	if err != nil {
-		return err
+		return fmt.Errorf("error opening input file: %w", err)
 	}
+	defer inFile.Close()
 
-	header := crypto.NewHeader(algID, blockSize, len(salt), alg.GetNonceSize(), salt)
-	encHeader, err := crypto.EncryptHeader(header)
+	out, err := openOutput(outPath, opts.Overwrite)
 	if err != nil {
 		return err
 	}
+	defer func() { err = closeOutput(out, err) }()
 
-	if outPath == "." {
-		outPath = f.Name + ".crpt"
-	}
+	return encryptStream(ctx, inFile, out, opts, f.PB)
+}
 
-	outFile, err := os.OpenFile(outPath, os.O_CREATE, 0644)
+// encryptStream writes the header and the chunks of everything read from r
+// to w. pb may be nil.
+func encryptStream(ctx context.Context, r io.Reader, w io.Writer, opts EncryptOptions, pb *progressbar.ProgressBar) error {
+	recipients, err := parseRecipients(opts.Recipients)
 	if err != nil {
-		return fmt.Errorf("error accessing the output file: %w", err)
+		return err
 	}
-	defer outFile.Close()
 
-	if _, err := outFile.Write(encHeader); err != nil {
-		return fmt.Errorf("error writing the header: %w", err)
-	}
+	pb.Start()
+	defer pb.Finish()
 
-	content, errs, err := file.ReadDecryptedFile(f.Path, blockSize)
+	return cryptool.Encrypt(ctx, w, r, cryptool.EncryptOptions{
+		Algorithm:  opts.Algorithm,
+		BlockSize:  opts.ChunkSize,
+		Passwords:  opts.PasswordSlots,
+		Recipients: recipients,
+		KDF:        opts.KDF,
+		Key:        opts.Key,
+		Content:    opts.content,
+		Jobs:       opts.Jobs,
+		Progress:   pb.Add,
+	})
+}
+
+// encryptDirectory packs root and its files into an archive and encrypts it
+// to outPath as a single file.
+func encryptDirectory(ctx context.Context, root os.FileInfo, files []*models.File, name, outPath string, opts EncryptOptions) (err error) {
+	out, err := openOutput(outPath, opts.Overwrite)
 	if err != nil {
 		return err
 	}
+	defer func() { err = closeOutput(out, err) }()
 
-	f.PB.Start()
-READ:
-	for {
-		select {
-		case plaintext, ok := <-content:
-			if !ok {
-				f.PB.Finish()
-				break READ
-			}
-
-			ciphertext, err := alg.Encrypt(plaintext)
-			if err != nil {
-				f.PB.Finish()
-				return err
-			}
-
-			if _, err := outFile.Write(ciphertext); err != nil {
-				f.PB.Finish()
-				return err
-			}
-
-			f.PB.Add(blockSize)
-		case err := <-errs:
-			if err != nil {
-				f.PB.Finish()
-				return fmt.Errorf("error reading file: %w", err)
-			}
+	var size int64
+	for _, f := range files {
+		if f.Info.Mode().IsRegular() {
+			size += f.Info.Size()
 		}
 	}
-	return nil
-}
+	pb := progressbar.New(progressbar.PrefixEncrypt+": "+name, size)
 
-func encryptDirectory(fs []*models.File, algorithm string, password []byte) error {
+	pr, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := archive.Write(ctx, pw, root, files)
+		pw.CloseWithError(err)
+		written <- err
+	}()
 
-	return nil
+	opts.content = crypto.ContentArchive
+	err = encryptStream(ctx, pr, out, opts, pb)
+	// unblocks the archive writer when encryption stops early
+	pr.CloseWithError(err)
+
+	writeErr := <-written
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return cryptool.Cancelled(ctx)
+	// a failed archive writer fails the encryption with its own error
+	case writeErr != nil && (err == nil || errors.Is(err, writeErr)):
+		return writeErr
+	}
+	return err
 }
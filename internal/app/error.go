@@ -0,0 +1,32 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
+)
+
+var (
+	ErrNoKey            = errors.New("a password, a recipient or a key file output is required")
+	ErrPasswordRequired = errors.New("file is encrypted with a password, a password is required")
+	ErrKeyfileRequired  = errors.New("file is encrypted with a key file, a key file is required")
+	ErrSlotKeyRequired  = errors.New("file is encrypted with key slots, a password or an identity is required")
+	ErrConflictingKeys  = errors.New("key slots and a key file cannot be combined")
+	ErrRekeyUnsupported = errors.New("file does not use key slots, re-encrypt it to change its keys")
+	ErrNewPasswordSlot  = errors.New("a new password replaces the slot opened by the current password")
+	ErrInvalidSlotIndex = errors.New("invalid key slot index")
+	ErrNoSlotsLeft      = errors.New("at least one key slot must be left")
+	ErrInvalidRange     = errors.New("invalid range")
+	ErrRangeOfStdin     = errors.New("a range can only be decrypted from a file")
+
+	ErrWrongKey  = cryptool.ErrWrongKey
+	ErrCorrupted = cryptool.ErrCorrupted
+	ErrCancelled = cryptool.ErrCancelled
+
+	ErrOutputExists = errors.New("output file exists, use --force to overwrite it or --no-clobber to skip it")
+	ErrOutputIsDir  = errors.New("output is a directory")
+
+	ErrMirrorNotDir = errors.New("--mirror requires a directory input")
+	ErrMirrorOutput = errors.New("--mirror requires an output directory other than the input directory")
+	ErrInputIsDir   = errors.New("input is a directory, decrypt a mirrored directory with --mirror")
+)
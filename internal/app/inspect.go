@@ -0,0 +1,298 @@
+package app
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/stream"
+	"github.com/DimaKropachev/cryptool/pkg/table"
+)
+
+type InspectOptions struct {
+	// JSON prints the result as JSON instead of a table
+	JSON bool
+}
+
+type inspection struct {
+	File          string          `json:"file"`
+	FileSize      int64           `json:"file_size"`
+	Version       uint8           `json:"version"`
+	Algorithm     string          `json:"algorithm"`
+	BlockSize     uint64          `json:"block_size"`
+	Salt          string          `json:"salt,omitempty"`
+	NonceSize     uint32          `json:"nonce_size"`
+	KeyMode       string          `json:"key_mode"`
+	Content       string          `json:"content"`
+	KDF           string          `json:"kdf,omitempty"`
+	KeySlots      []slotInfo      `json:"key_slots,omitempty"`
+	Extensions    []extensionInfo `json:"extensions"`
+	HeaderSize    int64           `json:"header_size"`
+	Chunks        uint64          `json:"chunks"`
+	PlaintextSize int64           `json:"plaintext_size"`
+	Problems      []string        `json:"problems"`
+}
+
+type slotInfo struct {
+	Index int    `json:"index"`
+	Type  string `json:"type"`
+	KDF   string `json:"kdf,omitempty"`
+}
+
+type extensionInfo struct {
+	Type     string `json:"type"`
+	Critical bool   `json:"critical"`
+	Size     int    `json:"size"`
+}
+
+// Inspect prints the header of an encrypted file and what can be checked
+// about it without a key.
+func Inspect(path string, opts InspectOptions) error {
+	info, err := inspectFile(path)
+	if err != nil {
+		return err
+	}
+
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	return info.render()
+}
+
+func inspectFile(path string) (*inspection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening input file: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error opening input file: %w", err)
+	}
+
+	header, err := crypto.DecryptHeader(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading header: %w", err)
+	}
+
+	headerSize, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &inspection{
+		File:       path,
+		FileSize:   stat.Size(),
+		Version:    header.Version,
+		BlockSize:  header.BlockSize,
+		NonceSize:  header.NonceSize,
+		HeaderSize: headerSize,
+		Extensions: []extensionInfo{},
+		Problems:   []string{},
+	}
+
+	// salts of versioned files are in the password slots
+	if len(header.Salt) != 0 {
+		info.Salt = hex.EncodeToString(header.Salt)
+	}
+
+	for _, ext := range header.Extensions {
+		info.Extensions = append(info.Extensions, extensionInfo{
+			Type:     ext.Type.String(),
+			Critical: ext.Critical,
+			Size:     len(ext.Value),
+		})
+	}
+
+	info.inspectKey(header)
+
+	if content, err := header.ContentType(); err != nil {
+		info.problem("%v", err)
+	} else {
+		info.Content = content.String()
+	}
+
+	if err := info.inspectChunks(header); err != nil {
+		info.problem("%v", err)
+	}
+
+	return info, nil
+}
+
+func (info *inspection) problem(format string, args ...any) {
+	info.Problems = append(info.Problems, fmt.Sprintf(format, args...))
+}
+
+func (info *inspection) inspectKey(header *crypto.Header) {
+	mode, err := header.KeyMode()
+	if err != nil {
+		info.problem("%v", err)
+		return
+	}
+	info.KeyMode = mode.String()
+
+	switch mode {
+	case crypto.KeyModePassword:
+		info.KDF = crypto.LegacyKDFParams().String()
+	case crypto.KeyModeSlots:
+		slots, err := header.KeySlots()
+		if err != nil {
+			info.problem("%v", err)
+			return
+		}
+		if len(slots) == 0 {
+			info.problem("file has no key slots and cannot be decrypted")
+		}
+
+		for i, slot := range slots {
+			s := slotInfo{Index: i, Type: slot.Type.String()}
+			if slot.Type == crypto.SlotPassword {
+				kdf, err := slot.KDFParams()
+				if err != nil {
+					info.problem("key slot %d: %v", i, err)
+				} else {
+					s.KDF = kdf.String()
+				}
+			}
+			info.KeySlots = append(info.KeySlots, s)
+		}
+	}
+}
+
+// inspectChunks derives the number of chunks and the plaintext size from the
+// file size, every chunk but the last one is exactly BlockSize long.
+func (info *inspection) inspectChunks(header *crypto.Header) error {
+	keySize, err := algorithms.KeySize(int(header.AlgID))
+	if err != nil {
+		return err
+	}
+
+	info.Algorithm, err = algorithms.NameByID(int(header.AlgID))
+	if err != nil {
+		return err
+	}
+
+	// sizes of the algorithm do not depend on the key
+	alg, err := algorithms.CreateAlgorithmByID(int(header.AlgID), make([]byte, keySize))
+	if err != nil {
+		return err
+	}
+
+	if int(header.NonceSize) != alg.GetNonceSize() {
+		return fmt.Errorf("nonce size %d does not match %s", header.NonceSize, info.Algorithm)
+	}
+
+	dec, err := stream.NewDecryptor(alg, header)
+	if err != nil {
+		return err
+	}
+
+	if header.BlockSize == 0 {
+		return fmt.Errorf("block size is zero")
+	}
+
+	data := info.FileSize - info.HeaderSize - int64(dec.HeaderTagSize())
+	if data < 0 {
+		return fmt.Errorf("file ends before the header tag")
+	}
+
+	overhead := int64(dec.ChunkNonceSize() + alg.GetTagSize())
+	chunkSize := int64(header.BlockSize) + overhead
+
+	if data == 0 {
+		if header.Version != crypto.Version0 {
+			return fmt.Errorf("final chunk is missing, the file is truncated")
+		}
+		return nil
+	}
+
+	info.Chunks = uint64((data + chunkSize - 1) / chunkSize)
+	if info.Chunks > stream.MaxChunks {
+		info.problem("%d chunks, at most %d are allowed", info.Chunks, uint64(stream.MaxChunks))
+	}
+
+	last := data - int64(info.Chunks-1)*chunkSize
+	if last < overhead {
+		return fmt.Errorf("last chunk is %s, shorter than its %d byte overhead", byteCount(last), overhead)
+	}
+
+	info.PlaintextSize = data - int64(info.Chunks)*overhead
+	return nil
+}
+
+func (info *inspection) render() error {
+	content := [][]string{
+		{"File", info.File},
+		{"File size", strconv.FormatInt(info.FileSize, 10)},
+		{"Version", strconv.Itoa(int(info.Version))},
+		{"Algorithm", info.Algorithm},
+		{"Block size", strconv.FormatUint(info.BlockSize, 10)},
+	}
+
+	if info.Salt != "" {
+		content = append(content, []string{"Salt", info.Salt})
+	}
+
+	content = append(content,
+		[]string{"Nonce size", strconv.FormatUint(uint64(info.NonceSize), 10)},
+		[]string{"Key mode", info.KeyMode},
+		[]string{"Content", info.Content},
+	)
+
+	if info.KDF != "" {
+		content = append(content, []string{"KDF", info.KDF})
+	}
+
+	for _, slot := range info.KeySlots {
+		value := slot.Type
+		if slot.KDF != "" {
+			value += ", " + slot.KDF
+		}
+		content = append(content, []string{fmt.Sprintf("Key slot %d", slot.Index), value})
+	}
+
+	for _, ext := range info.Extensions {
+		value := byteCount(int64(ext.Size))
+		if ext.Critical {
+			value += ", critical"
+		}
+		content = append(content, []string{"Extension " + ext.Type, value})
+	}
+
+	content = append(content,
+		[]string{"Header size", strconv.FormatInt(info.HeaderSize, 10)},
+		[]string{"Chunks", strconv.FormatUint(info.Chunks, 10)},
+		[]string{"Plaintext size", strconv.FormatInt(info.PlaintextSize, 10)},
+	)
+
+	problems := "none"
+	if len(info.Problems) != 0 {
+		problems = strings.Join(info.Problems, "\n")
+	}
+	content = append(content, []string{"Problems", problems})
+
+	table := table.New()
+	table.SetHeader([]string{"Field", "Value"})
+	if err := table.SetContent(content); err != nil {
+		return err
+	}
+	return table.Render()
+}
+
+// byteCount formats n as a number of bytes.
+func byteCount(n int64) string {
+	if n == 1 {
+		return "1 byte"
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
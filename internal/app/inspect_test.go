@@ -0,0 +1,99 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
+)
+
+func TestInspectChunks(t *testing.T) {
+	const block = cryptool.MinBlockSize
+
+	encrypted := func(size int) []byte {
+		out := bytes.NewBuffer([]byte{})
+		opts := EncryptOptions{Algorithm: algorithms.AlgAES256GCM, Key: crypto.GenerateKey(32), ChunkSize: block}
+		if err := encryptStream(context.Background(), bytes.NewReader(crypto.GenerateKey(size)), out, opts, nil); err != nil {
+			t.Fatal(err)
+		}
+		return out.Bytes()
+	}
+
+	// a v0 header of an empty file, the block size was the file size
+	salt := crypto.GenerateSalt(crypto.DefaultSaltSize)
+	v0 := crypto.NewHeader(algorithms.IDAES256GCM, 0, len(salt), 12, salt, nil)
+	v0.Version = crypto.Version0
+	zeroBlock, err := crypto.EncryptHeader(v0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := os.ReadFile("../../pkg/cryptool/testdata/v0-chacha20-poly1305.crpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	boundary := encrypted(2 * block)
+	// header and header tag of a file with no chunks
+	headerSize := len(encrypted(0)) - 16
+
+	type Case struct {
+		name      string
+		data      []byte
+		chunks    uint64
+		plaintext int64
+		problem   string
+	}
+
+	cases := []Case{
+		{name: "empty", data: encrypted(0), chunks: 1, plaintext: 0},
+		{name: "one byte", data: encrypted(1), chunks: 1, plaintext: 1},
+		{name: "chunk boundary", data: boundary, chunks: 2, plaintext: 2 * block},
+		{name: "past chunk boundary", data: encrypted(2*block + 1), chunks: 3, plaintext: 2*block + 1},
+		{name: "last chunk removed", data: boundary[:len(boundary)-(block+16)], chunks: 1, plaintext: block},
+		{name: "no chunks", data: boundary[:headerSize], problem: "final chunk is missing"},
+		{name: "cut in the header tag", data: boundary[:headerSize-8], problem: "file ends before the header tag"},
+		{name: "last chunk too short", data: boundary[:len(boundary)-(block+16)+10], chunks: 2, problem: "last chunk is 10 bytes"},
+		{name: "last chunk of one byte", data: boundary[:len(boundary)-(block+16)+1], chunks: 2, problem: "last chunk is 1 byte,"},
+		{name: "zero block size", data: zeroBlock, problem: "block size is zero"},
+		{name: "legacy", data: legacy, chunks: 3, plaintext: 2514},
+	}
+
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), "file.crpt")
+		if err := os.WriteFile(path, c.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := inspectFile(path)
+		if err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, nil)
+		}
+
+		problems := strings.Join(info.Problems, "\n")
+		if c.problem == "" && len(info.Problems) != 0 {
+			t.Fatalf("[%s] get problems: %q, expected none", c.name, problems)
+		}
+		if !strings.Contains(problems, c.problem) {
+			t.Fatalf("[%s] get problems: %q, expected: %q", c.name, problems, c.problem)
+		}
+
+		// only v0 headers carry a salt
+		if (info.Salt != "") != (info.Version == crypto.Version0) {
+			t.Fatalf("[%s] get salt: %q for version %d", c.name, info.Salt, info.Version)
+		}
+
+		if info.Chunks != c.chunks {
+			t.Fatalf("[%s] get %d chunks, expected: %d", c.name, info.Chunks, c.chunks)
+		}
+		if c.problem == "" && info.PlaintextSize != c.plaintext {
+			t.Fatalf("[%s] get plaintext size: %d, expected: %d", c.name, info.PlaintextSize, c.plaintext)
+		}
+	}
+}
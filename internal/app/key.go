@@ -0,0 +1,166 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
+)
+
+// newRawKey generates the random key used when no password is given and
+// writes it to the key file, so the output can be decrypted.
+func newRawKey(algorithm, keyfilePath, format string) ([]byte, error) {
+	algID, err := algorithms.IDByName(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	keySize, err := algorithms.KeySize(algID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := crypto.GenerateKey(keySize)
+
+	data, err := crypto.EncodeKey(key, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// an existing key file may still be needed for other files
+	keyfile, err := os.OpenFile(keyfilePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the key file: %w", err)
+	}
+	defer keyfile.Close()
+
+	if _, err := keyfile.Write(data); err != nil {
+		return nil, fmt.Errorf("error writing the key file: %w", err)
+	}
+
+	return key, nil
+}
+
+func wrapKey(key []byte, passwords [][]byte, recipients []string, kdf *crypto.KDFParams) ([]crypto.KeySlot, error) {
+	parsed, err := parseRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.WrapKey(key, passwords, parsed, kdf)
+}
+
+func parseRecipients(recipients []string) ([]*crypto.Recipient, error) {
+	parsed := make([]*crypto.Recipient, 0, len(recipients))
+	for _, r := range recipients {
+		recipient, err := crypto.ParseRecipient(r)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, recipient)
+	}
+
+	return parsed, nil
+}
+
+// decryptOptions returns the library options that open files with header:
+// the key file is decoded for the algorithm of the header and the identity
+// files are read. A key the header cannot be opened with is reported before
+// any key derivation.
+func decryptOptions(header *crypto.Header, opts DecryptOptions) (cryptool.DecryptOptions, error) {
+	limits := opts.kdfLimits()
+	libOpts := cryptool.DecryptOptions{KDFLimits: &limits, Jobs: opts.Jobs}
+
+	mode, err := header.KeyMode()
+	if err != nil {
+		return libOpts, fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
+	}
+
+	switch mode {
+	case crypto.KeyModeRawKey:
+		if opts.Keyfile == "" {
+			return libOpts, ErrKeyfileRequired
+		}
+
+		keySize, err := algorithms.KeySize(int(header.AlgID))
+		if err != nil {
+			return libOpts, fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
+		}
+
+		data, err := os.ReadFile(opts.Keyfile)
+		if err != nil {
+			return libOpts, fmt.Errorf("error reading the key file: %w", err)
+		}
+
+		libOpts.Key, err = crypto.DecodeKey(data, keySize)
+		if errors.Is(err, crypto.ErrInvalidKeyFile) {
+			return libOpts, fmt.Errorf("%w: %w", ErrWrongKey, err)
+		}
+		if err != nil {
+			return libOpts, err
+		}
+	case crypto.KeyModeSlots:
+		if len(opts.Password) == 0 && len(opts.Identities) == 0 {
+			return libOpts, ErrSlotKeyRequired
+		}
+
+		identities, err := readIdentities(opts.Identities)
+		if err != nil {
+			return libOpts, err
+		}
+		libOpts.Identities = identities
+	default:
+		if len(opts.Password) == 0 {
+			return libOpts, ErrPasswordRequired
+		}
+	}
+
+	if len(opts.Password) != 0 {
+		libOpts.Passwords = [][]byte{opts.Password}
+	}
+
+	return libOpts, nil
+}
+
+// openSlots unwraps the file key from the first key slot opened by the
+// password or one of the identities and returns the index of that slot.
+func openSlots(header *crypto.Header, keySize int, opts DecryptOptions) ([]byte, int, error) {
+	slots, err := header.KeySlots()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	identities, err := readIdentities(opts.Identities)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	passwords := [][]byte{}
+	if len(opts.Password) != 0 {
+		passwords = append(passwords, opts.Password)
+	}
+
+	return crypto.UnwrapKey(slots, keySize, passwords, identities, opts.kdfLimits())
+}
+
+func readIdentities(paths []string) ([]*crypto.Identity, error) {
+	identities := []*crypto.Identity{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the identity file: %w", err)
+		}
+
+		ids, err := crypto.ParseIdentities(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading the identity file %s: %w", path, err)
+		}
+		identities = append(identities, ids...)
+	}
+
+	return identities, nil
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+)
+
+func TestEncryptKeyfileOut(t *testing.T) {
+	type Case struct {
+		name      string
+		input     bool
+		output    bool
+		overwrite Overwrite
+		err       error
+		keyfile   bool
+	}
+
+	cases := []Case{
+		{name: "encrypted", input: true, keyfile: true},
+		{name: "missing input", err: os.ErrNotExist},
+		{name: "existing output", input: true, output: true, err: ErrOutputExists},
+		{name: "skipped output", input: true, output: true, overwrite: OverwriteSkip},
+	}
+
+	for _, c := range cases {
+		dir := t.TempDir()
+		inPath := filepath.Join(dir, "plain")
+		outPath := filepath.Join(dir, "plain.crpt")
+		keyfile := filepath.Join(dir, "key")
+
+		if c.input {
+			if err := os.WriteFile(inPath, []byte("secret"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if c.output {
+			if err := os.WriteFile(outPath, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		opts := EncryptOptions{
+			Algorithm:     algorithms.AlgAES256GCM,
+			KeyfileOut:    keyfile,
+			KeyfileFormat: crypto.KeyFormatRaw,
+			Overwrite:     c.overwrite,
+		}
+		err := Encrypt(context.Background(), inPath, outPath, opts)
+		if (c.err == nil) != (err == nil) || !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+
+		// a key file without an output it opens is not left behind
+		_, err = os.Stat(keyfile)
+		if c.keyfile != (err == nil) {
+			t.Fatalf("[%s] get key file: %v, expected: %v", c.name, err == nil, c.keyfile)
+		}
+	}
+}
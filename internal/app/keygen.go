@@ -0,0 +1,42 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+)
+
+// Keygen generates an X25519 identity and writes it to outPath, or to stdout
+// when outPath is empty. The public key is printed so it can be shared.
+func Keygen(outPath string) error {
+	identity, err := crypto.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+	recipient := identity.Recipient().String()
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		// never overwrite an identity, files encrypted for it would be lost
+		f, err := os.OpenFile(outPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("error creating the identity file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	_, err = fmt.Fprintf(out, "# created: %s\n# public key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), recipient, identity)
+	if err != nil {
+		return fmt.Errorf("error writing the identity file: %w", err)
+	}
+
+	if outPath != "" {
+		fmt.Fprintf(os.Stdout, "Public key: %s\n", recipient)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DimaKropachev/cryptool/pkg/file"
+	"github.com/DimaKropachev/cryptool/pkg/models"
+	"github.com/DimaKropachev/cryptool/pkg/progressbar"
+)
+
+// mirrorFunc processes the file f to outPath.
+type mirrorFunc func(ctx context.Context, f *models.File, outPath string) error
+
+// encryptMirror encrypts every file under inPath to its own file with the
+// .crpt extension at the same relative path under outDir. It returns the
+// number of encrypted files, also when it fails.
+func encryptMirror(ctx context.Context, inPath, outDir string, opts EncryptOptions) (int, error) {
+	rename := func(name string) (string, bool) {
+		return name + ".crpt", true
+	}
+	encrypt := func(ctx context.Context, f *models.File, outPath string) error {
+		return encryptFile(ctx, f, outPath, opts)
+	}
+
+	n, err := mirror(ctx, inPath, outDir, progressbar.PrefixEncrypt, rename, encrypt, opts.Overwrite)
+	if err != nil {
+		return n, err
+	}
+
+	fmt.Fprintf(os.Stdout, "Directory %s successfully encrypted to %s, %d files\n", inPath, outDir, n)
+	return n, nil
+}
+
+// decryptMirror reverses encryptMirror: every .crpt file under inPath is
+// decrypted to the same relative path under outDir without the extension.
+func decryptMirror(ctx context.Context, inPath, outDir string, opts DecryptOptions) error {
+	rename := func(name string) (string, bool) {
+		return strings.CutSuffix(name, ".crpt")
+	}
+	decrypt := func(ctx context.Context, f *models.File, outPath string) error {
+		return decryptFile(ctx, f, outPath, opts)
+	}
+
+	n, err := mirror(ctx, inPath, outDir, progressbar.PrefixDecrypt, rename, decrypt, opts.Overwrite)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Directory %s successfully decrypted to %s, %d files\n", inPath, outDir, n)
+	return nil
+}
+
+// mirror recreates the directories under inPath in outDir and processes
+// every regular file whose name rename accepts, one after another, each
+// with its own progress bar. Other files are reported as skipped. It
+// returns the number of processed files.
+func mirror(ctx context.Context, inPath, outDir, prefix string, rename func(string) (string, bool), process mirrorFunc, overwrite Overwrite) (int, error) {
+	if outDir == StdStream {
+		return 0, ErrMirrorOutput
+	}
+
+	absIn, err := filepath.Abs(inPath)
+	if err != nil {
+		return 0, err
+	}
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return 0, err
+	}
+	if absIn == absOut {
+		return 0, ErrMirrorOutput
+	}
+
+	files, err := file.ReadDirectory(inPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return 0, fmt.Errorf("error creating the output directory: %w", err)
+	}
+
+	// notices are printed after the progress bars are gone
+	var notices []string
+	defer func() {
+		for _, notice := range notices {
+			fmt.Fprintln(os.Stdout, notice)
+		}
+	}()
+
+	pull := progressbar.NewPull()
+	var queue []*models.File
+	var outPaths []string
+
+	for _, f := range files {
+		// an output directory inside the input directory is not mirrored
+		if absPath, err := filepath.Abs(f.Path); err == nil && (absPath == absOut || strings.HasPrefix(absPath, absOut+string(filepath.Separator))) {
+			continue
+		}
+
+		switch mode := f.Info.Mode(); {
+		case mode.IsDir():
+			if err := os.MkdirAll(filepath.Join(outDir, f.Name), 0755); err != nil {
+				return 0, fmt.Errorf("error creating the output directory: %w", err)
+			}
+		case mode.IsRegular():
+			name, ok := rename(f.Name)
+			if !ok {
+				notices = append(notices, fmt.Sprintf("File %s is not encrypted, skipped", f.Path))
+				continue
+			}
+			f.PB = pull.Add(prefix+": "+f.Name, f.Info.Size())
+			queue = append(queue, f)
+			outPaths = append(outPaths, filepath.Join(outDir, name))
+		default:
+			notices = append(notices, fmt.Sprintf("File %s is not a regular file, skipped", f.Path))
+		}
+	}
+
+	if len(queue) == 0 {
+		return 0, nil
+	}
+
+	// without a terminal the bars stay hidden
+	if err := pull.Start(); err == nil {
+		defer pull.Stop()
+	}
+
+	n := 0
+	for i, f := range queue {
+		err := process(ctx, f, outPaths[i])
+		if skipped(err, overwrite) {
+			f.PB.Finish()
+			notices = append(notices, fmt.Sprintf("File %s exists, skipped", outPaths[i]))
+			continue
+		}
+		if err != nil {
+			return n, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+)
+
+func TestMirror(t *testing.T) {
+	dir := t.TempDir()
+	key := crypto.GenerateKey(32)
+
+	keyfile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyfile, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		filepath.Join("a", "b", "x.txt"): "hello",
+		filepath.Join("a", "y.txt"):      "world",
+		"z.txt":                          "",
+	}
+
+	src := filepath.Join(dir, "src")
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(src, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	encOpts := EncryptOptions{Algorithm: algorithms.AlgAES256GCM, Key: key, Mirror: true}
+	decOpts := DecryptOptions{Keyfile: keyfile, Mirror: true}
+
+	encDir := filepath.Join(dir, "enc")
+	if err := Encrypt(context.Background(), src, encDir, encOpts); err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+
+	for name := range files {
+		if _, err := os.Stat(filepath.Join(encDir, name+".crpt")); err != nil {
+			t.Fatalf("[%s] encrypted file is missing: %v", name, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(encDir, "empty")); err != nil || !info.IsDir() {
+		t.Fatalf("empty directory is not mirrored: %v", err)
+	}
+
+	// files without the extension are left out
+	if err := os.WriteFile(filepath.Join(encDir, "notes.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	decDir := filepath.Join(dir, "dec")
+	if err := Decrypt(context.Background(), encDir, decDir, decOpts); err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(decDir, name))
+		if err != nil || string(data) != content {
+			t.Fatalf("[%s] get content: %q (%v), expected content: %q", name, data, err, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(decDir, "notes.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("file without the extension is decrypted: %v", err)
+	}
+
+	type Case struct {
+		name string
+		run  func() error
+		err  error
+	}
+
+	cases := []Case{
+		{
+			name: "existing output",
+			run:  func() error { return Encrypt(context.Background(), src, encDir, encOpts) },
+			err:  ErrOutputExists,
+		},
+		{
+			name: "skip existing output",
+			run: func() error {
+				opts := encOpts
+				opts.Overwrite = OverwriteSkip
+				return Encrypt(context.Background(), src, encDir, opts)
+			},
+		},
+		{
+			name: "output is input",
+			run:  func() error { return Encrypt(context.Background(), src, src, encOpts) },
+			err:  ErrMirrorOutput,
+		},
+		{
+			name: "file input",
+			run: func() error {
+				return Encrypt(context.Background(), filepath.Join(src, "z.txt"), filepath.Join(dir, "out"), encOpts)
+			},
+			err: ErrMirrorNotDir,
+		},
+		{
+			name: "directory without mirror",
+			run: func() error {
+				return Decrypt(context.Background(), encDir, filepath.Join(dir, "out"), DecryptOptions{Keyfile: keyfile})
+			},
+			err: ErrInputIsDir,
+		},
+	}
+
+	for _, c := range cases {
+		if err := c.run(); !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Overwrite tells what happens when the output file already exists.
+type Overwrite int
+
+const (
+	// OverwriteRefuse fails with ErrOutputExists
+	OverwriteRefuse Overwrite = iota
+	// OverwriteForce replaces the file
+	OverwriteForce
+	// OverwriteSkip leaves the file as it is and reports success
+	OverwriteSkip
+)
+
+// output is written to a temporary file in the directory of path, which
+// replaces path on commit. Until then an existing file at path is untouched,
+// a failed or cancelled run leaves neither a partial nor a stale file.
+type output struct {
+	io.Writer
+	path string
+	tmp  *os.File
+	// mode of the replaced file, zero for a new file
+	mode os.FileMode
+	// replace allows commit to replace a file created at path meanwhile
+	replace bool
+}
+
+func openOutput(path string, overwrite Overwrite) (*output, error) {
+	return openOutputTo(os.Stdout, path, overwrite)
+}
+
+// openOutputTo is openOutput with stdout written to w.
+func openOutputTo(stdout io.Writer, path string, overwrite Overwrite) (*output, error) {
+	if path == StdStream {
+		return &output{Writer: stdout, path: path}, nil
+	}
+
+	mode := os.FileMode(0)
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if info.IsDir() {
+			return nil, fmt.Errorf("%w: %s", ErrOutputIsDir, path)
+		}
+		if overwrite != OverwriteForce {
+			return nil, fmt.Errorf("%w: %s", ErrOutputExists, path)
+		}
+		mode = info.Mode().Perm()
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("error accessing the output file: %w", err)
+	}
+
+	tmp, err := createTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-", 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary file: %w", err)
+	}
+
+	return &output{Writer: tmp, path: path, tmp: tmp, mode: mode, replace: overwrite == OverwriteForce}, nil
+}
+
+// createTemp creates a new file named prefix and a random suffix in dir.
+// Unlike os.CreateTemp the file gets perm reduced by the umask, like any
+// other new file, instead of 0600.
+func createTemp(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, os.ErrExist) && try < 10000 {
+			continue
+		}
+		return f, err
+	}
+}
+
+// commit syncs the temporary file and renames it to path. Unless out may
+// replace a file, path is checked again as the file is moved there: the file
+// may have been created since openOutput.
+func (o *output) commit() error {
+	if o.tmp == nil {
+		return nil
+	}
+
+	if o.mode != 0 {
+		if err := o.tmp.Chmod(o.mode); err != nil {
+			o.abort()
+			return fmt.Errorf("error writing the output file: %w", err)
+		}
+	}
+	if err := o.tmp.Sync(); err != nil {
+		o.abort()
+		return fmt.Errorf("error writing the output file: %w", err)
+	}
+	if err := o.tmp.Close(); err != nil {
+		os.Remove(o.tmp.Name())
+		return fmt.Errorf("error writing the output file: %w", err)
+	}
+
+	if o.replace {
+		if err := os.Rename(o.tmp.Name(), o.path); err != nil {
+			os.Remove(o.tmp.Name())
+			return fmt.Errorf("error replacing the output file: %w", err)
+		}
+	} else if err := moveNew(o.tmp.Name(), o.path); err != nil {
+		os.Remove(o.tmp.Name())
+		return err
+	}
+
+	return syncDir(filepath.Dir(o.path))
+}
+
+// moveNew moves the file tmp to path, which must not exist. A hard link
+// fails on an existing path where a rename would replace it. Without hard
+// links path is created exclusively and then replaced by tmp.
+func moveNew(tmp, path string) error {
+	err := os.Link(tmp, path)
+	switch {
+	case err == nil:
+		os.Remove(tmp)
+		return nil
+	case errors.Is(err, os.ErrExist):
+		return fmt.Errorf("%w: %s", ErrOutputExists, path)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrOutputExists, path)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating the output file: %w", err)
+	}
+	f.Close()
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("error creating the output file: %w", err)
+	}
+	return nil
+}
+
+// abort removes the temporary file.
+func (o *output) abort() {
+	if o.tmp == nil {
+		return
+	}
+
+	o.tmp.Close()
+	os.Remove(o.tmp.Name())
+}
+
+// closeOutput commits out if err is nil and aborts it otherwise.
+func closeOutput(out *output, err error) error {
+	if err != nil {
+		out.abort()
+		return err
+	}
+	return out.commit()
+}
+
+// outputDir is extracted to a temporary directory next to path, which
+// replaces path on commit.
+type outputDir struct {
+	path string
+	tmp  string
+	// replace allows commit to replace path
+	replace bool
+}
+
+func openOutputDir(path string, overwrite Overwrite) (*outputDir, error) {
+	_, err := os.Lstat(path)
+	switch {
+	case err == nil:
+		if overwrite != OverwriteForce {
+			return nil, fmt.Errorf("%w: %s", ErrOutputExists, path)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("error accessing the output directory: %w", err)
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary directory: %w", err)
+	}
+
+	return &outputDir{path: path, tmp: tmp, replace: overwrite == OverwriteForce}, nil
+}
+
+// commit renames the temporary directory to path. A replaced path is moved
+// aside first and removed after the rename, as a rename does not replace a
+// directory.
+func (o *outputDir) commit() error {
+	old := o.tmp + ".old"
+
+	_, err := os.Lstat(o.path)
+	replace := err == nil
+	// path may have been created since openOutputDir
+	if replace && !o.replace {
+		o.abort()
+		return fmt.Errorf("%w: %s", ErrOutputExists, o.path)
+	}
+	if replace {
+		if err := os.Rename(o.path, old); err != nil {
+			o.abort()
+			return fmt.Errorf("error replacing the output directory: %w", err)
+		}
+	}
+
+	if err := os.Rename(o.tmp, o.path); err != nil {
+		if replace {
+			os.Rename(old, o.path)
+		}
+		o.abort()
+		return fmt.Errorf("error replacing the output directory: %w", err)
+	}
+
+	if err := syncDir(filepath.Dir(o.path)); err != nil {
+		return err
+	}
+
+	if replace {
+		if err := os.RemoveAll(old); err != nil {
+			return fmt.Errorf("error removing the replaced output directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// abort removes the temporary directory.
+func (o *outputDir) abort() {
+	os.RemoveAll(o.tmp)
+}
+
+// closeOutputDir commits out if err is nil and aborts it otherwise.
+func closeOutputDir(out *outputDir, err error) error {
+	if err != nil {
+		out.abort()
+		return err
+	}
+	return out.commit()
+}
+
+// skipped reports whether err is an existing output that should be left
+// alone rather than reported.
+func skipped(err error, overwrite Overwrite) bool {
+	return overwrite == OverwriteSkip && errors.Is(err, ErrOutputExists)
+}
+
+func reportSkipped(outPath string) error {
+	fmt.Fprintf(os.Stdout, "File %s exists, skipped\n", outPath)
+	return nil
+}
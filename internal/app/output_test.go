@@ -0,0 +1,100 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOutput(t *testing.T) {
+	old := bytes.Repeat([]byte("old data "), 100)
+	data := []byte("new data")
+
+	type Case struct {
+		name   string
+		exists bool
+		// created after the output is opened
+		meanwhile bool
+		overwrite Overwrite
+		fail      bool
+		err       error
+		result    []byte
+	}
+
+	cases := []Case{
+		{name: "new file", overwrite: OverwriteRefuse, result: data},
+		{name: "refused", exists: true, overwrite: OverwriteRefuse, err: ErrOutputExists, result: old},
+		{name: "skipped", exists: true, overwrite: OverwriteSkip, err: ErrOutputExists, result: old},
+		{name: "forced over a larger file", exists: true, overwrite: OverwriteForce, result: data},
+		{name: "failed run", exists: true, overwrite: OverwriteForce, fail: true, err: errors.New("failed"), result: old},
+		{name: "refused after open", meanwhile: true, overwrite: OverwriteRefuse, err: ErrOutputExists, result: old},
+		{name: "skipped after open", meanwhile: true, overwrite: OverwriteSkip, err: ErrOutputExists, result: old},
+		{name: "forced after open", meanwhile: true, overwrite: OverwriteForce, result: data},
+	}
+
+	for _, c := range cases {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "out")
+
+		if c.exists {
+			if err := os.WriteFile(path, old, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := func() (err error) {
+			out, err := openOutput(path, c.overwrite)
+			if err != nil {
+				return err
+			}
+			defer func() { err = closeOutput(out, err) }()
+
+			if c.meanwhile {
+				if err := os.WriteFile(path, old, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := out.Write(data); err != nil {
+				return err
+			}
+			if c.fail {
+				return c.err
+			}
+			return nil
+		}()
+		if (err == nil) != (c.err == nil) || (c.err != nil && !c.fail && !errors.Is(err, c.err)) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+
+		result, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, nil)
+		}
+		if !bytes.Equal(result, c.result) {
+			t.Fatalf("[%s] get output %q, expected: %q", c.name, result, c.result)
+		}
+
+		// the mode of a replaced file is kept, windows only has a read-only bit
+		if c.exists && runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Fatalf("[%s] get mode %v, expected: %v", c.name, info.Mode().Perm(), os.FileMode(0600))
+			}
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("[%s] get %d files, expected: 1, temporary files are left", c.name, len(entries))
+		}
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package app
+
+import (
+	"fmt"
+	"os"
+)
+
+// syncDir syncs the directory at path, so a file renamed into it keeps its
+// new name after a crash.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error syncing the output directory: %w", err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("error syncing the output directory: %w", err)
+	}
+	return nil
+}
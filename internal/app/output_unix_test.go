@@ -0,0 +1,55 @@
+//go:build !windows
+
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestOutputMode(t *testing.T) {
+	type Case struct {
+		name     string
+		existing os.FileMode
+		mode     os.FileMode
+	}
+
+	cases := []Case{
+		{name: "new file", mode: 0600},
+		{name: "replaced file", existing: 0640, mode: 0640},
+	}
+
+	// a new file gets 0644 reduced by the umask
+	defer syscall.Umask(syscall.Umask(077))
+
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), "out")
+
+		if c.existing != 0 {
+			if err := os.WriteFile(path, nil, c.existing); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(path, c.existing); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		out, err := openOutput(path, OverwriteForce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := closeOutput(out, nil); err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, nil)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != c.mode {
+			t.Fatalf("[%s] get mode %v, expected: %v", c.name, info.Mode().Perm(), c.mode)
+		}
+	}
+}
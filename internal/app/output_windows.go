@@ -0,0 +1,8 @@
+//go:build windows
+
+package app
+
+// syncDir does nothing, directories cannot be synced on Windows.
+func syncDir(path string) error {
+	return nil
+}
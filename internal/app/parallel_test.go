@@ -0,0 +1,35 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
+)
+
+func TestEncryptStreamFormat(t *testing.T) {
+	key := crypto.GenerateKey(32)
+	plaintext := crypto.GenerateKey(3*cryptool.MinBlockSize + 100)
+
+	out := bytes.NewBuffer([]byte{})
+	opts := EncryptOptions{Algorithm: algorithms.AlgAES256GCM, Key: key, ChunkSize: cryptool.MinBlockSize, Jobs: 4}
+	if err := encryptStream(context.Background(), bytes.NewReader(plaintext), out, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := cryptool.NewDecryptReader(out, cryptool.DecryptOptions{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("decrypted data does not match")
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
+)
+
+// ByteRange is a range of plaintext bytes, End is exclusive. A negative End
+// stands for the end of the file.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+// ParseRange parses "start:end", either side may be empty.
+func ParseRange(s string) (*ByteRange, error) {
+	start, end, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("%w: %q, expected start:end", ErrInvalidRange, s)
+	}
+
+	r := &ByteRange{End: -1}
+
+	var err error
+	if start != "" {
+		if r.Start, err = strconv.ParseInt(start, 10, 64); err != nil || r.Start < 0 {
+			return nil, fmt.Errorf("%w: start %q", ErrInvalidRange, start)
+		}
+	}
+	if end != "" {
+		if r.End, err = strconv.ParseInt(end, 10, 64); err != nil || r.End < r.Start {
+			return nil, fmt.Errorf("%w: end %q", ErrInvalidRange, end)
+		}
+	}
+
+	return r, nil
+}
+
+// decryptRange decrypts only the chunks holding the range.
+func decryptRange(ctx context.Context, inPath, outPath string, opts DecryptOptions) (err error) {
+	inFile, err := os.Open(inPath)
+	if err != nil {
+		return fmt.Errorf("error opening input file: %w", err)
+	}
+	defer inFile.Close()
+
+	info, err := inFile.Stat()
+	if err != nil {
+		return fmt.Errorf("error opening input file: %w", err)
+	}
+
+	header, err := crypto.DecryptHeader(io.NewSectionReader(inFile, 0, info.Size()))
+	if err != nil {
+		return fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
+	}
+
+	libOpts, err := decryptOptions(header, opts)
+	if err != nil {
+		return err
+	}
+
+	ra, err := cryptool.NewDecryptReaderAt(inFile, info.Size(), libOpts)
+	if err != nil {
+		return err
+	}
+
+	start, end := opts.Range.Start, opts.Range.End
+	if end < 0 || end > ra.Size() {
+		end = ra.Size()
+	}
+	if start > end {
+		return fmt.Errorf("%w: start %d is past the end of the %d byte plaintext", ErrInvalidRange, start, ra.Size())
+	}
+
+	out, err := openOutput(outPath, opts.Overwrite)
+	if err != nil {
+		return err
+	}
+	defer func() { err = closeOutput(out, err) }()
+
+	section := io.NewSectionReader(ra, start, end-start)
+	if _, err := io.Copy(out, contextReader{ctx: ctx, r: section}); err != nil {
+		return err
+	}
+
+	return nil
+}
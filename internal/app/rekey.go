@@ -0,0 +1,206 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/stream"
+)
+
+type RekeyOptions struct {
+	// Password and Identities unlock one of the current key slots
+	Password   []byte
+	Identities []string
+	// NewPassword replaces the password slot opened by Password
+	NewPassword      []byte
+	AddPasswordSlots [][]byte
+	AddRecipients    []string
+	// RemoveSlots are indexes of the current key slots
+	RemoveSlots []int
+	// KDF derives the wrap keys of new password slots
+	KDF *crypto.KDFParams
+	// KDFLimit multiplies the key derivation cost limits of the current
+	// slots, see DecryptOptions
+	KDFLimit uint32
+}
+
+// Rekey changes the key slots of an encrypted file. The file key stays the
+// same, so the chunks are not re-encrypted, but they are copied: the file
+// with the new header is written next to the old one and replaces it in a
+// single rename. Overwriting the header in place would leave no usable key
+// slots if it were interrupted, so rekeying costs a copy of the file.
+func Rekey(path string, opts RekeyOptions) error {
+	if opts.KDF == nil {
+		opts.KDF = crypto.DefaultKDFParams(crypto.KDFPBKDF2)
+	}
+
+	inFile, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening input file: %w", err)
+	}
+	defer inFile.Close()
+
+	info, err := inFile.Stat()
+	if err != nil {
+		return fmt.Errorf("error opening input file: %w", err)
+	}
+
+	header, err := crypto.DecryptHeader(inFile)
+	if err != nil {
+		return fmt.Errorf("error reading header: %w", err)
+	}
+
+	mode, err := header.KeyMode()
+	if err != nil {
+		return fmt.Errorf("error reading header: %w", err)
+	}
+	if mode != crypto.KeyModeSlots {
+		return ErrRekeyUnsupported
+	}
+
+	if len(opts.Password) == 0 && len(opts.Identities) == 0 {
+		return ErrSlotKeyRequired
+	}
+
+	keySize, err := algorithms.KeySize(int(header.AlgID))
+	if err != nil {
+		return fmt.Errorf("error reading header: %w", err)
+	}
+
+	key, opened, err := openSlots(header, keySize, DecryptOptions{Password: opts.Password, Identities: opts.Identities, KDFLimit: opts.KDFLimit})
+	if err != nil {
+		return err
+	}
+
+	alg, err := algorithms.CreateAlgorithmByID(int(header.AlgID), key)
+	if err != nil {
+		return fmt.Errorf("error creating algorithm: %w", err)
+	}
+
+	dec, err := stream.NewDecryptor(alg, header)
+	if err != nil {
+		return fmt.Errorf("error reading header: %w", err)
+	}
+
+	// the header tag does not cover the key slots and stays valid
+	headerTag := make([]byte, dec.HeaderTagSize())
+	if _, err := io.ReadFull(inFile, headerTag); err != nil {
+		return fmt.Errorf("error reading header: %w", err)
+	}
+	if err := dec.OpenHeader(headerTag); err != nil {
+		return err
+	}
+
+	slots, err := header.KeySlots()
+	if err != nil {
+		return err
+	}
+
+	slots, err = rekeySlots(slots, opened, key, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := header.SetKeySlots(slots); err != nil {
+		return err
+	}
+
+	encHeader, err := crypto.EncryptHeader(header)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceHeader(inFile, info, encHeader, headerTag); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Key slots of %s updated, %d slots\n", filepath.Base(path), len(slots))
+	return nil
+}
+
+// replaceHeader copies the rest of f, positioned after the header tag, to a
+// new file with encHeader and replaces f with it.
+func replaceHeader(f *os.File, info os.FileInfo, encHeader, headerTag []byte) error {
+	path := f.Name()
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".rekey-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if _, err := tmpFile.Write(encHeader); err != nil {
+		return fmt.Errorf("error writing the header: %w", err)
+	}
+	if _, err := tmpFile.Write(headerTag); err != nil {
+		return fmt.Errorf("error writing the header: %w", err)
+	}
+	if _, err := io.Copy(tmpFile, f); err != nil {
+		return fmt.Errorf("error copying chunks: %w", err)
+	}
+
+	if err := tmpFile.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	f.Close()
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("error replacing the file: %w", err)
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// rekeySlots applies the changes of opts to the key slots. opened is the
+// index of the slot the file key was unwrapped from.
+func rekeySlots(slots []crypto.KeySlot, opened int, key []byte, opts RekeyOptions) ([]crypto.KeySlot, error) {
+	if len(opts.NewPassword) != 0 {
+		if len(opts.Password) == 0 || slots[opened].Type != crypto.SlotPassword {
+			return nil, ErrNewPasswordSlot
+		}
+
+		slot, err := crypto.WrapWithPassword(opts.NewPassword, key, opts.KDF)
+		if err != nil {
+			return nil, err
+		}
+		slots[opened] = *slot
+	}
+
+	remove := make([]bool, len(slots))
+	for _, i := range opts.RemoveSlots {
+		if i < 0 || i >= len(slots) {
+			return nil, fmt.Errorf("%w: %d", ErrInvalidSlotIndex, i)
+		}
+		remove[i] = true
+	}
+
+	kept := slices.Clone(slots)[:0]
+	for i, slot := range slots {
+		if !remove[i] {
+			kept = append(kept, slot)
+		}
+	}
+
+	added, err := wrapKey(key, opts.AddPasswordSlots, opts.AddRecipients, opts.KDF)
+	if err != nil {
+		return nil, err
+	}
+	kept = append(kept, added...)
+
+	if len(kept) == 0 {
+		return nil, ErrNoSlotsLeft
+	}
+
+	return kept, nil
+}
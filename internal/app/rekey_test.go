@@ -0,0 +1,186 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+)
+
+func TestRekey(t *testing.T) {
+	kdf := &crypto.KDFParams{KDF: crypto.KDFPBKDF2, Time: 1000}
+
+	identity, err := crypto.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// many recipients do not fit into the reserved space
+	recipients := []string{}
+	for range 64 {
+		id, err := crypto.GenerateIdentity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		recipients = append(recipients, id.Recipient().String())
+	}
+
+	type Case struct {
+		name string
+		opts RekeyOptions
+		// passwords that open the file afterwards and those that do not
+		opens    []string
+		rejected []string
+		err      error
+	}
+
+	cases := []Case{
+		{
+			name:  "add password",
+			opts:  RekeyOptions{Password: []byte("ops"), AddPasswordSlots: [][]byte{[]byte("ci")}},
+			opens: []string{"ops", "ci"},
+		},
+		{
+			name:     "remove password",
+			opts:     RekeyOptions{Identities: []string{"identity"}, RemoveSlots: []int{0}},
+			rejected: []string{"ops"},
+		},
+		{
+			name:     "new password",
+			opts:     RekeyOptions{Password: []byte("ops"), NewPassword: []byte("dev")},
+			opens:    []string{"dev"},
+			rejected: []string{"ops"},
+		},
+		{
+			name:  "outgrown header",
+			opts:  RekeyOptions{Password: []byte("ops"), AddRecipients: recipients},
+			opens: []string{"ops"},
+		},
+		{
+			name:     "wrong password",
+			opts:     RekeyOptions{Password: []byte("ci"), AddPasswordSlots: [][]byte{[]byte("dev")}},
+			opens:    []string{"ops"},
+			rejected: []string{"dev"},
+			err:      crypto.ErrNoMatchingSlot,
+		},
+		{
+			name: "no slots left",
+			opts: RekeyOptions{Password: []byte("ops"), RemoveSlots: []int{0, 1}},
+			err:  ErrNoSlotsLeft,
+		},
+	}
+
+	for _, c := range cases {
+		dir := t.TempDir()
+		plainPath := filepath.Join(dir, "plain")
+		encPath := filepath.Join(dir, "plain.crpt")
+		identityPath := filepath.Join(dir, "identity")
+
+		plaintext := crypto.GenerateKey(3*4096 + 100)
+		if err := os.WriteFile(plainPath, plaintext, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(identityPath, []byte(identity.String()+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		encOpts := EncryptOptions{
+			Algorithm:  algorithms.AlgAES256GCM,
+			Password:   []byte("ops"),
+			Recipients: []string{identity.Recipient().String()},
+			KDF:        kdf,
+			ChunkSize:  4096,
+		}
+		if err := Encrypt(context.Background(), plainPath, encPath, encOpts); err != nil {
+			t.Fatal(err)
+		}
+
+		before, headerSize := readEncrypted(t, encPath)
+		beforeInfo, err := os.Stat(encPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c.opts.KDF = kdf
+		for i, id := range c.opts.Identities {
+			c.opts.Identities[i] = filepath.Join(dir, id)
+		}
+
+		err = Rekey(encPath, c.opts)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+
+		after, newHeaderSize := readEncrypted(t, encPath)
+		if !bytes.Equal(after[newHeaderSize:], before[headerSize:]) {
+			t.Fatalf("[%s] chunks changed", c.name)
+		}
+
+		afterInfo, err := os.Stat(encPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the file is replaced, never written in place
+		if os.SameFile(beforeInfo, afterInfo) != (c.err != nil) {
+			t.Fatalf("[%s] get file replaced: %v, expected: %v", c.name, !os.SameFile(beforeInfo, afterInfo), c.err == nil)
+		}
+		if c.err != nil && !bytes.Equal(after, before) {
+			t.Fatalf("[%s] file changed after an error", c.name)
+		}
+		if entries, err := os.ReadDir(dir); err != nil || len(entries) != 3 {
+			t.Fatalf("[%s] get %d files left (%v), expected: 3", c.name, len(entries), err)
+		}
+
+		for _, password := range append(c.opens, c.rejected...) {
+			out := filepath.Join(t.TempDir(), "out")
+			err := Decrypt(context.Background(), encPath, out, DecryptOptions{Password: []byte(password)})
+
+			if slices.Contains(c.rejected, password) {
+				if !errors.Is(err, ErrWrongKey) {
+					t.Fatalf("[%s] password %q: get error: %v, expected error: %v", c.name, password, err, ErrWrongKey)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("[%s] password %q: get error: %v, expected error: %v", c.name, password, err, nil)
+			}
+
+			result, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(result, plaintext) {
+				t.Fatalf("[%s] password %q: decrypted data does not match", c.name, password)
+			}
+		}
+	}
+}
+
+// readEncrypted returns the encrypted file and the size of its header and
+// header tag.
+func readEncrypted(t *testing.T, path string) ([]byte, int) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := crypto.DecryptHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encHeader, err := crypto.EncryptHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the header tag of an AES-GCM file
+	return data, len(encHeader) + 16
+}
@@ -0,0 +1,4 @@
+package app
+
+// StdStream as an input path reads stdin, as an output path writes stdout.
+const StdStream = "-"
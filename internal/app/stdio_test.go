@@ -0,0 +1,74 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
+)
+
+func TestStdStreamRoundTrip(t *testing.T) {
+	const block = cryptool.MinBlockSize
+
+	// anything printed to the real stdout would be mixed into piped data
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	saved := os.Stdout
+	os.Stdout = stdout
+	defer func() { os.Stdout = saved }()
+
+	encOpts := EncryptOptions{
+		Algorithm:     algorithms.AlgCHACHA20POLY1305,
+		PasswordSlots: [][]byte{[]byte("password")},
+		KDF:           &crypto.KDFParams{KDF: crypto.KDFPBKDF2, Time: 1000},
+		ChunkSize:     block,
+	}
+	decOpts := DecryptOptions{Password: []byte("password")}
+
+	for _, size := range []int{0, 1, block, 2*block + 1} {
+		plaintext := crypto.GenerateKey(size)
+
+		encrypted := bytes.NewBuffer([]byte{})
+		if err := encryptStdin(context.Background(), bytes.NewReader(plaintext), encrypted, StdStream, encOpts); err != nil {
+			t.Fatalf("[%d bytes] get error: %v, expected error: %v", size, err, nil)
+		}
+
+		decrypted := bytes.NewBuffer([]byte{})
+		if err := decryptStdin(context.Background(), encrypted, decrypted, StdStream, decOpts); err != nil {
+			t.Fatalf("[%d bytes] get error: %v, expected error: %v", size, err, nil)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plaintext) {
+			t.Fatalf("[%d bytes] decrypted data does not match", size)
+		}
+	}
+
+	// an output file leaves stdout empty as well
+	path := filepath.Join(t.TempDir(), "file.crpt")
+	unused := bytes.NewBuffer([]byte{})
+	if err := encryptStdin(context.Background(), bytes.NewReader([]byte("secret")), unused, path, encOpts); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decryptStdin(context.Background(), bytes.NewReader(encrypted), unused, StdStream, decOpts); err != nil {
+		t.Fatal(err)
+	}
+	if unused.String() != "secret" {
+		t.Fatalf("get %q, expected: %q", unused.String(), "secret")
+	}
+
+	if info, err := stdout.Stat(); err != nil || info.Size() != 0 {
+		printed, _ := os.ReadFile(stdout.Name())
+		t.Fatalf("get %q printed to stdout, expected nothing", printed)
+	}
+}
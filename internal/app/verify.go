@@ -0,0 +1,46 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/DimaKropachev/cryptool/pkg/progressbar"
+)
+
+// Verify opens every chunk of an encrypted file like Decrypt does, but the
+// plaintext is discarded instead of written.
+func Verify(ctx context.Context, path string, opts DecryptOptions) error {
+	path = filepath.Clean(path)
+
+	inFile, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening input file: %w", err)
+	}
+	defer inFile.Close()
+
+	info, err := inFile.Stat()
+	if err != nil {
+		return fmt.Errorf("error opening input file: %w", err)
+	}
+
+	header, r, err := peekHeader(inFile)
+	if err != nil {
+		return err
+	}
+
+	libOpts, err := decryptOptions(header, opts)
+	if err != nil {
+		return err
+	}
+
+	pb := progressbar.New(progressbar.PrefixVerify+": "+info.Name(), info.Size())
+	if err := decryptStream(ctx, r, io.Discard, libOpts, pb); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "File %s is intact\n", info.Name())
+	return nil
+}
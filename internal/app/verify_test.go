@@ -0,0 +1,88 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/stream"
+	"github.com/DimaKropachev/cryptool/pkg/cryptool"
+)
+
+func TestVerify(t *testing.T) {
+	const block = cryptool.MinBlockSize
+
+	dir := t.TempDir()
+	password := []byte("password")
+
+	// three full chunks, the last one is flagged as last
+	out := bytes.NewBuffer([]byte{})
+	opts := EncryptOptions{
+		Algorithm:     algorithms.AlgAES256GCM,
+		PasswordSlots: [][]byte{password},
+		KDF:           &crypto.KDFParams{KDF: crypto.KDFPBKDF2, Time: 1000},
+		ChunkSize:     block,
+	}
+	if err := encryptStream(context.Background(), bytes.NewReader(crypto.GenerateKey(3*block)), out, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := out.Bytes()
+	chunkSize := block + 16
+	body := len(encrypted) - 3*chunkSize
+
+	modified := bytes.Clone(encrypted)
+	modified[body+chunkSize+100] ^= 1
+
+	header := bytes.Clone(encrypted)
+	header[5] ^= 1
+
+	legacy, err := os.ReadFile("../../pkg/cryptool/testdata/v0-aes256-gcm.crpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type Case struct {
+		name     string
+		data     []byte
+		password string
+		err      error
+		cause    error
+	}
+
+	cases := []Case{
+		{name: "intact", data: encrypted, password: "password"},
+		{name: "wrong password", data: encrypted, password: "wrong", err: ErrWrongKey, cause: crypto.ErrNoMatchingSlot},
+		{name: "modified header", data: header, password: "password", err: ErrCorrupted},
+		{name: "modified chunk", data: modified, password: "password", err: ErrCorrupted, cause: stream.ErrChunkAuthentication},
+		{name: "cut in a chunk", data: encrypted[:len(encrypted)-100], password: "password", err: ErrCorrupted, cause: stream.ErrChunkAuthentication},
+		{name: "last chunk removed", data: encrypted[:len(encrypted)-chunkSize], password: "password", err: ErrCorrupted, cause: stream.ErrTruncated},
+		{name: "data after the last chunk", data: append(bytes.Clone(encrypted), encrypted[body:body+chunkSize]...), password: "password", err: ErrCorrupted, cause: stream.ErrTrailingData},
+		{name: "legacy", data: legacy, password: "password"},
+		{name: "legacy wrong password", data: legacy, password: "wrong", err: ErrWrongKey},
+	}
+
+	for _, c := range cases {
+		path := filepath.Join(dir, "file.crpt")
+		if err := os.WriteFile(path, c.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := Verify(context.Background(), path, DecryptOptions{Password: []byte(c.password)})
+		if !errors.Is(err, c.err) || (c.err == nil && err != nil) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+		if c.cause != nil && !errors.Is(err, c.cause) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.cause)
+		}
+
+		// the exit codes tell a wrong key from a damaged file
+		if errors.Is(err, ErrWrongKey) && errors.Is(err, ErrCorrupted) {
+			t.Fatalf("[%s] get error: %v, both a wrong key and a damaged file", c.name, err)
+		}
+	}
+}
@@ -23,33 +23,109 @@ This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
-			fmt.Fprintln(os.Stderr, "")
-			os.Exit(0)
+			fmt.Fprintln(os.Stderr, "a file to decrypt is required, - reads stdin")
+			os.Exit(1)
 		}
 		inputPath := args[0]
 
-		// flag "password"
-		password, err := cmd.Flags().GetString("password")
+		// flag "output"
+		outputPath, err := cmd.Flags().GetString("output")
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(0)
+			os.Exit(1)
 		}
 
-		// flag "output"
-		outputPath, err := cmd.Flags().GetString("output")
+		// flag "keyfile"
+		keyfile, err := cmd.Flags().GetString("keyfile")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "jobs"
+		jobs, err := cmd.Flags().GetInt("jobs")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "identity"
+		identities, err := cmd.Flags().GetStringArray("identity")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "range"
+		var byteRange *app.ByteRange
+		if cmd.Flags().Changed("range") {
+			rangeFlag, err := cmd.Flags().GetString("range")
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			byteRange, err = app.ParseRange(rangeFlag)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+
+		// flags "force" and "no-clobber"
+		overwrite, err := getOverwrite(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "mirror"
+		mirror, err := getMirror(cmd)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(0)
+			os.Exit(1)
+		}
+		if mirror && byteRange != nil {
+			fmt.Fprintln(os.Stderr, "--mirror and --range cannot be used together")
+			os.Exit(1)
+		}
+
+		// flag "kdf-limit"
+		kdfLimit, err := cmd.Flags().GetUint32("kdf-limit")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flags "password", "password-file", "password-fd"
+		// without a key file or identity the password comes from the environment or a prompt
+		var password []byte
+		if passwordGiven(cmd) || (keyfile == "" && len(identities) == 0) {
+			password, err = getPassword(cmd, false)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
 		}
 
 		err = app.Decrypt(
+			cmd.Context(),
 			inputPath,
 			outputPath,
-			[]byte(password),
+			app.DecryptOptions{
+				Password:   password,
+				Keyfile:    keyfile,
+				Identities: identities,
+				Jobs:       jobs,
+				Range:      byteRange,
+				Overwrite:  overwrite,
+				Mirror:     mirror,
+				KDFLimit:   kdfLimit,
+			},
 		)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(0)
+			os.Exit(1)
 		}
 	},
 }
@@ -66,6 +142,14 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// decryptCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	decryptCmd.Flags().StringP("output", "o", "", "")
-	decryptCmd.Flags().StringP("password", "p", "", "")
+	decryptCmd.Flags().StringP("output", "o", "", "output file or directory of an archive, - writes stdout (default for stdin input)")
+	addPasswordFlags(decryptCmd)
+	decryptCmd.Flags().String("keyfile", "", "key file written by encrypt --keyfile-out")
+	decryptCmd.Flags().IntP("jobs", "j", 0, "number of chunks decrypted in parallel (default GOMAXPROCS)")
+	decryptCmd.Flags().StringArray("identity", nil, "identity file written by keygen, can be repeated")
+	decryptCmd.Flags().BoolP("force", "f", false, "overwrite the output file if it exists")
+	decryptCmd.Flags().BoolP("no-clobber", "n", false, "skip the file if the output file exists")
+	decryptCmd.Flags().Bool("mirror", false, "decrypt every .crpt file of a directory encrypted with encrypt --mirror to the --output directory")
+	decryptCmd.Flags().Uint32("kdf-limit", 1, "multiplies the key derivation cost a file may ask for, raise it only for trusted files")
+	decryptCmd.Flags().String("range", "", "decrypt only plaintext bytes start:end (end exclusive, either may be empty), writes stdout without -o")
 }
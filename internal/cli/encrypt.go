@@ -4,11 +4,17 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 
 	"github.com/DimaKropachev/cryptool/internal/app"
+	"github.com/DimaKropachev/cryptool/pkg/config"
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	mem "github.com/DimaKropachev/cryptool/pkg/memory"
+	"github.com/DimaKropachev/cryptool/pkg/password"
 	"github.com/spf13/cobra"
 )
 
@@ -25,23 +31,16 @@ to quickly create a Cobra application.`,
 
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
-			fmt.Fprintln(os.Stderr, "")
-			os.Exit(0)
+			fmt.Fprintln(os.Stderr, "a file or directory to encrypt is required, - reads stdin")
+			os.Exit(1)
 		}
 		inputPath := filepath.Clean(args[0])
 
-		// flag "password"
-		password, err := cmd.Flags().GetString("password")
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(0)
-		}
-
 		// flag "output"
 		outputPath, err := cmd.Flags().GetString("output")
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(0)
+			os.Exit(1)
 		}
 		outputPath = filepath.Clean(outputPath)
 
@@ -49,18 +48,144 @@ to quickly create a Cobra application.`,
 		alg, err := cmd.Flags().GetString("algorithm")
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(0)
+			os.Exit(1)
+		}
+
+		// flags "kdf", "kdf-time", "kdf-memory", "kdf-threads"
+		kdf, err := getKDFParams(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "keyfile-out"
+		keyfileOut, err := cmd.Flags().GetString("keyfile-out")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "keyfile-format"
+		keyfileFormat, err := cmd.Flags().GetString("keyfile-format")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "recipient"
+		recipients, err := cmd.Flags().GetStringArray("recipient")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-		
+
+		// flags "password-slot", "password-slot-file", "password-slot-fd"
+		passwordSlots, err := getSlotPasswords(cmd, "password-slot")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "jobs"
+		jobs, err := cmd.Flags().GetInt("jobs")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "chunk-size"
+		chunkSizeFlag, err := cmd.Flags().GetString("chunk-size")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		chunkSize, err := mem.ParseBytes(chunkSizeFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "generate-password"
+		generate, err := cmd.Flags().GetBool("generate-password")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if passwordGiven(cmd) && keyfileOut != "" {
+			fmt.Fprintln(os.Stderr, "--password and --keyfile-out cannot be used together")
+			os.Exit(1)
+		}
+		if generate && (passwordGiven(cmd) || keyfileOut != "") {
+			fmt.Fprintln(os.Stderr, "--generate-password cannot be used with a password or --keyfile-out")
+			os.Exit(1)
+		}
+
+		// flags "password", "password-file", "password-fd"
+		// without another key the password comes from the environment or a prompt
+		var password []byte
+		if generate {
+			password, err = generatePassphrase()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		} else if passwordGiven(cmd) || (keyfileOut == "" && len(recipients) == 0 && len(passwordSlots) == 0) {
+			password, err = getPassword(cmd, true)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if prompted(cmd) {
+				reportStrength(password, kdf)
+			}
+		}
+
+		// flags "min-strength" and "allow-weak"
+		passwords := passwordSlots
+		if password != nil {
+			passwords = append([][]byte{password}, passwordSlots...)
+		}
+		if err := checkPasswords(cmd, kdf, passwords...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flags "force" and "no-clobber"
+		overwrite, err := getOverwrite(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "mirror"
+		mirror, err := getMirror(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
 		err = app.Encrypt(
-			alg,
+			cmd.Context(),
 			inputPath,
 			outputPath,
-			[]byte(password),
+			app.EncryptOptions{
+				Algorithm:     alg,
+				Password:      password,
+				KDF:           kdf,
+				KeyfileOut:    keyfileOut,
+				KeyfileFormat: keyfileFormat,
+				Recipients:    recipients,
+				PasswordSlots: passwordSlots,
+				Jobs:          jobs,
+				ChunkSize:     int(min(chunkSize, math.MaxInt32)),
+				Overwrite:     overwrite,
+				Mirror:        mirror,
+			},
 		)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(0)
+			os.Exit(1)
 		}
 	},
 }
@@ -77,7 +202,113 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// encryptCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	encryptCmd.Flags().StringP("output", "o", "", "")
-	encryptCmd.Flags().StringP("password", "p", "", "")
+	encryptCmd.Flags().StringP("output", "o", "", "output file, - writes stdout (default for stdin input), a directory is packed into one file")
+	addPasswordFlags(encryptCmd)
 	encryptCmd.Flags().StringP("algorithm", "a", "aes256-gcm", "")
+	encryptCmd.Flags().String("kdf", crypto.KDFNamePBKDF2, "password key derivation function: pbkdf2, scrypt or argon2id (default from kdf calibrate --save)")
+	encryptCmd.Flags().Uint32("kdf-time", 0, "pbkdf2 iterations or argon2id passes")
+	encryptCmd.Flags().Uint32("kdf-memory", 0, "argon2id memory or scrypt N, in KiB")
+	encryptCmd.Flags().Uint8("kdf-threads", 0, "argon2id threads or scrypt parallelization")
+	encryptCmd.Flags().String("keyfile-out", "", "encrypt with a random key and write it to this file instead of using a password")
+	encryptCmd.Flags().String("keyfile-format", crypto.KeyFormatRaw, "key file format: raw, hex or base64")
+	encryptCmd.Flags().StringArray("recipient", nil, "encrypt for a public key from keygen, can be repeated")
+	encryptCmd.Flags().String("chunk-size", "1MiB", "plaintext size of a chunk, from 4KiB to 64MiB")
+	encryptCmd.Flags().IntP("jobs", "j", 0, "number of chunks encrypted in parallel (default GOMAXPROCS)")
+	addSlotPasswordFlags(encryptCmd, "password-slot", "add a password that unlocks the file, can be repeated and combined with --recipient")
+	encryptCmd.Flags().Bool("generate-password", false, "encrypt with a random passphrase and print it once to stderr")
+	encryptCmd.Flags().Int("min-strength", password.DefaultMinScore, "lowest accepted password strength from 0 (very weak) to 4 (very strong), default from the config file")
+	encryptCmd.Flags().Bool("allow-weak", false, "accept passwords below --min-strength")
+	encryptCmd.Flags().BoolP("force", "f", false, "overwrite the output file if it exists")
+	encryptCmd.Flags().BoolP("no-clobber", "n", false, "skip the file if the output file exists")
+	encryptCmd.Flags().Bool("mirror", false, "encrypt every file of a directory to its own file in the --output directory")
+}
+
+func getKDFParams(cmd *cobra.Command) (*crypto.KDFParams, error) {
+	name, err := cmd.Flags().GetString("kdf")
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// without --kdf the calibrated default is used
+	if !cmd.Flags().Changed("kdf") && cfg.DefaultKDF != "" {
+		name = cfg.DefaultKDF
+	}
+
+	kdf, err := crypto.ParseKDF(name)
+	if err != nil {
+		return nil, err
+	}
+
+	params := cfg.KDFParams(kdf)
+	if params == nil {
+		params = crypto.DefaultKDFParams(kdf)
+	}
+
+	if cmd.Flags().Changed("kdf-time") {
+		if params.Time, err = cmd.Flags().GetUint32("kdf-time"); err != nil {
+			return nil, err
+		}
+	}
+
+	if cmd.Flags().Changed("kdf-memory") {
+		if params.Memory, err = cmd.Flags().GetUint32("kdf-memory"); err != nil {
+			return nil, err
+		}
+	}
+
+	if cmd.Flags().Changed("kdf-threads") {
+		if params.Threads, err = cmd.Flags().GetUint8("kdf-threads"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := params.CheckLimits(crypto.DefaultKDFLimits); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s is costlier than decrypt allows by default, it will need --kdf-limit\n", params)
+	}
+
+	return params, nil
+}
+
+func getOverwrite(cmd *cobra.Command) (app.Overwrite, error) {
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return 0, err
+	}
+
+	noClobber, err := cmd.Flags().GetBool("no-clobber")
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case force && noClobber:
+		return 0, errors.New("--force and --no-clobber cannot be used together")
+	case force:
+		return app.OverwriteForce, nil
+	case noClobber:
+		return app.OverwriteSkip, nil
+	}
+	return app.OverwriteRefuse, nil
+}
+
+// getMirror returns the flag "mirror", which needs an output directory.
+func getMirror(cmd *cobra.Command) (bool, error) {
+	mirror, err := cmd.Flags().GetBool("mirror")
+	if err != nil {
+		return false, err
+	}
+
+	if mirror && !cmd.Flags().Changed("output") {
+		return false, errors.New("--mirror requires an --output directory")
+	}
+	return mirror, nil
 }
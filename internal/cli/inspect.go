@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DimaKropachev/cryptool/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Show the header of an encrypted file",
+	Long: `Prints the header of a .crpt file without decrypting it: algorithm, block size,
+salt, key derivation parameters, key slots, the number of chunks, the expected
+plaintext size and any problems found in the file layout.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "a file to inspect is required")
+			os.Exit(1)
+		}
+
+		// flag "json"
+		jsonOutput, err := cmd.Flags().GetBool("json")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if err := app.Inspect(args[0], app.InspectOptions{JSON: jsonOutput}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().Bool("json", false, "print the result as JSON")
+}
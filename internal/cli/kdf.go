@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/DimaKropachev/cryptool/internal/app"
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/spf13/cobra"
+)
+
+// kdfCmd represents the kdf command
+var kdfCmd = &cobra.Command{
+	Use:   "kdf",
+	Short: "Manage password key derivation parameters",
+}
+
+// kdfCalibrateCmd represents the kdf calibrate command
+var kdfCalibrateCmd = &cobra.Command{
+	Use:   "calibrate",
+	Short: "Pick key derivation parameters for this machine",
+	Long: `Measures every supported key derivation function on this machine and prints
+the parameters that unlock a file in about the target time within the memory budget.
+
+With --save the parameters are stored in the config file and used by encrypt
+as defaults, for example:
+
+  cryptool kdf calibrate --target 500ms --memory 256 --save`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// flag "target"
+		target, err := cmd.Flags().GetDuration("target")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "memory"
+		memory, err := cmd.Flags().GetUint32("memory")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "save"
+		save, err := cmd.Flags().GetBool("save")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "default"
+		defaultKDF, err := cmd.Flags().GetString("default")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		kdf, err := crypto.ParseKDF(defaultKDF)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// the budget in KiB does not fit in uint32 above 4 TiB
+		maxMemory := uint32(min(uint64(memory)*1024, math.MaxUint32))
+
+		err = app.CalibrateKDF(app.CalibrateOptions{
+			Target:     target,
+			MaxMemory:  maxMemory,
+			Save:       save,
+			DefaultKDF: kdf,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(kdfCmd)
+	kdfCmd.AddCommand(kdfCalibrateCmd)
+
+	kdfCalibrateCmd.Flags().Duration("target", 500*time.Millisecond, "time it should take to unlock a file")
+	kdfCalibrateCmd.Flags().Uint32("memory", 64, "memory budget of scrypt and argon2id, in MiB")
+	kdfCalibrateCmd.Flags().Bool("save", false, "save the parameters as encrypt defaults")
+	kdfCalibrateCmd.Flags().String("default", crypto.KDFNameArgon2id, "kdf used by encrypt when --kdf is not given")
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DimaKropachev/cryptool/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// keygenCmd represents the keygen command
+var keygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate an X25519 identity for public-key encryption",
+	Long: `Generates an identity (private key) and prints its public key. Files encrypted
+with encrypt --recipient <public key> are decrypted with decrypt --identity <file>:
+
+  cryptool keygen -o key.txt
+  cryptool encrypt --recipient crpt-pk-... secret.txt
+  cryptool decrypt --identity key.txt secret.txt.crpt`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// flag "output"
+		outputPath, err := cmd.Flags().GetString("output")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if err := app.Keygen(outputPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(keygenCmd)
+
+	keygenCmd.Flags().StringP("output", "o", "", "identity file, stdout if empty")
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/DimaKropachev/cryptool/pkg/password"
+	"github.com/spf13/cobra"
+)
+
+var charsets = map[string]string{
+	"lower":   password.CharsLower,
+	"upper":   password.CharsUpper,
+	"digits":  password.CharsDigits,
+	"symbols": password.CharsSymbols,
+}
+
+// passgenCmd represents the passgen command
+var passgenCmd = &cobra.Command{
+	Use:   "passgen",
+	Short: "Generate a random passphrase or password",
+	Long: `Generates passphrases of random words from an embedded diceware wordlist, or
+passwords of random characters with --chars. The passwords are printed to
+stdout and their entropy in bits to stderr:
+
+  cryptool passgen
+  cryptool passgen --words 10 --separator " "
+  cryptool passgen --chars --length 32 --charset lower,upper,digits`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// flag "count"
+		count, err := cmd.Flags().GetInt("count")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if count < 1 {
+			fmt.Fprintln(os.Stderr, "--count must be at least 1")
+			os.Exit(1)
+		}
+
+		var entropy float64
+		for range count {
+			generated, err := generatePassword(cmd)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stdout, "%s\n", generated.Password)
+			entropy = generated.Entropy
+		}
+
+		fmt.Fprintf(os.Stderr, "Entropy: %.1f bits\n", entropy)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(passgenCmd)
+
+	passgenCmd.Flags().IntP("words", "w", password.DefaultWords, "number of words of a passphrase")
+	passgenCmd.Flags().StringP("separator", "s", password.DefaultSeparator, "separator of the words")
+	passgenCmd.Flags().Bool("chars", false, "generate random characters instead of words")
+	passgenCmd.Flags().IntP("length", "l", password.DefaultLength, "number of characters with --chars")
+	passgenCmd.Flags().StringSlice("charset", []string{"lower", "upper", "digits", "symbols"}, "character sets with --chars: lower, upper, digits and symbols")
+	passgenCmd.Flags().IntP("count", "c", 1, "number of passwords to generate")
+}
+
+// generatePassword generates a password as the passgen flags of cmd describe.
+func generatePassword(cmd *cobra.Command) (password.Generated, error) {
+	// flag "chars"
+	chars, err := cmd.Flags().GetBool("chars")
+	if err != nil {
+		return password.Generated{}, err
+	}
+
+	if !chars {
+		// flags "words" and "separator"
+		words, err := cmd.Flags().GetInt("words")
+		if err != nil {
+			return password.Generated{}, err
+		}
+		separator, err := cmd.Flags().GetString("separator")
+		if err != nil {
+			return password.Generated{}, err
+		}
+		return password.GeneratePassphrase(words, separator)
+	}
+
+	// flags "length" and "charset"
+	length, err := cmd.Flags().GetInt("length")
+	if err != nil {
+		return password.Generated{}, err
+	}
+	names, err := cmd.Flags().GetStringSlice("charset")
+	if err != nil {
+		return password.Generated{}, err
+	}
+
+	var charset strings.Builder
+	for _, name := range names {
+		set, ok := charsets[name]
+		if !ok {
+			return password.Generated{}, fmt.Errorf("unknown character set: %s, expected lower, upper, digits or symbols", name)
+		}
+		charset.WriteString(set)
+	}
+
+	return password.GenerateChars(length, charset.String())
+}
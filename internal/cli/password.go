@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/DimaKropachev/cryptool/pkg/config"
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/password"
+	"github.com/spf13/cobra"
+)
+
+func addPasswordFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("password", "p", "", "password, visible in the shell history and process list, prefer the prompt or --password-file")
+	cmd.Flags().String("password-file", "", "read the password from the first line of this file")
+	cmd.Flags().Int("password-fd", 0, "read the password from the first line of this file descriptor")
+}
+
+// passwordGiven reports whether a password source flag is set.
+func passwordGiven(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("password") || cmd.Flags().Changed("password-file") || cmd.Flags().Changed("password-fd")
+}
+
+// getPassword reads the password from the source flags, the environment or
+// the terminal. confirm asks twice at the prompt.
+func getPassword(cmd *cobra.Command, confirm bool) ([]byte, error) {
+	src := password.Sources{FD: -1}
+
+	var err error
+	if src.Password, err = cmd.Flags().GetString("password"); err != nil {
+		return nil, err
+	}
+	if src.File, err = cmd.Flags().GetString("password-file"); err != nil {
+		return nil, err
+	}
+	if cmd.Flags().Changed("password-fd") {
+		if src.FD, err = cmd.Flags().GetInt("password-fd"); err != nil {
+			return nil, err
+		}
+	}
+
+	return password.Read(src, confirm)
+}
+
+func addNewPasswordFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("change-password", false, "replace the slot of the current password, the new one is asked for on the terminal")
+	cmd.Flags().String("new-password", "", "new password, visible in the shell history and process list, prefer the prompt or --new-password-file")
+	cmd.Flags().String("new-password-file", "", "read the new password from the first line of this file")
+	cmd.Flags().Int("new-password-fd", 0, "read the new password from the first line of this file descriptor")
+}
+
+// newPasswordGiven reports whether a new password source flag is set.
+func newPasswordGiven(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("new-password") || cmd.Flags().Changed("new-password-file") || cmd.Flags().Changed("new-password-fd")
+}
+
+// getNewPassword reads the new password from the source flags or asks for it
+// twice on the terminal. The environment holds the current password and is
+// not used.
+func getNewPassword(cmd *cobra.Command) ([]byte, error) {
+	if !newPasswordGiven(cmd) {
+		p, err := password.PromptConfirm("Enter new password: ", "Confirm new password: ")
+		if errors.Is(err, password.ErrNoTerminal) {
+			return nil, errors.New("no terminal to ask for the new password on, use --new-password-file or --new-password-fd")
+		}
+		return p, err
+	}
+
+	src := password.Sources{FD: -1}
+
+	var err error
+	if src.Password, err = cmd.Flags().GetString("new-password"); err != nil {
+		return nil, err
+	}
+	if src.File, err = cmd.Flags().GetString("new-password-file"); err != nil {
+		return nil, err
+	}
+	if cmd.Flags().Changed("new-password-fd") {
+		if src.FD, err = cmd.Flags().GetInt("new-password-fd"); err != nil {
+			return nil, err
+		}
+	}
+
+	return password.Read(src, false)
+}
+
+// addSlotPasswordFlags adds the flag name with the passwords of extra slots
+// and the flags name-file and name-fd to read them from files and file
+// descriptors instead of the command line.
+func addSlotPasswordFlags(cmd *cobra.Command, name, usage string) {
+	cmd.Flags().StringArray(name, nil, usage+", visible in the shell history and process list, prefer --"+name+"-file")
+	cmd.Flags().StringArray(name+"-file", nil, "read a slot password from the first line of this file, can be repeated")
+	cmd.Flags().IntSlice(name+"-fd", nil, "read a slot password from the first line of this file descriptor, can be repeated")
+}
+
+// getSlotPasswords returns the passwords of the flags added by
+// addSlotPasswordFlags.
+func getSlotPasswords(cmd *cobra.Command, name string) ([][]byte, error) {
+	args, err := cmd.Flags().GetStringArray(name)
+	if err != nil {
+		return nil, err
+	}
+	files, err := cmd.Flags().GetStringArray(name + "-file")
+	if err != nil {
+		return nil, err
+	}
+	fds, err := cmd.Flags().GetIntSlice(name + "-fd")
+	if err != nil {
+		return nil, err
+	}
+
+	passwords := make([][]byte, 0, len(args)+len(files)+len(fds))
+	for _, p := range args {
+		if p == "" {
+			return nil, password.ErrEmpty
+		}
+		passwords = append(passwords, []byte(p))
+	}
+	for _, path := range files {
+		p, err := password.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		passwords = append(passwords, p)
+	}
+	for _, fd := range fds {
+		p, err := password.ReadFD(fd)
+		if err != nil {
+			return nil, err
+		}
+		passwords = append(passwords, p)
+	}
+	return passwords, nil
+}
+
+// prompted reports whether getPassword asks on the terminal.
+func prompted(cmd *cobra.Command) bool {
+	_, env := os.LookupEnv(password.EnvVar)
+	return !passwordGiven(cmd) && !env
+}
+
+// getMinStrength returns --min-strength, the configured minimum or the
+// default, in that order.
+func getMinStrength(cmd *cobra.Command) (int, error) {
+	score, err := cmd.Flags().GetInt("min-strength")
+	if err != nil {
+		return 0, err
+	}
+
+	if !cmd.Flags().Changed("min-strength") {
+		cfg, err := config.Load()
+		if err != nil {
+			return 0, err
+		}
+		if cfg.MinPasswordStrength != nil {
+			score = *cfg.MinPasswordStrength
+		}
+	}
+
+	if err := password.ValidateScore(score); err != nil {
+		return 0, err
+	}
+	return score, nil
+}
+
+// checkPasswords applies the minimum strength policy to the passwords
+// unless --allow-weak is set.
+func checkPasswords(cmd *cobra.Command, params *crypto.KDFParams, passwords ...[]byte) error {
+	allowWeak, err := cmd.Flags().GetBool("allow-weak")
+	if err != nil {
+		return err
+	}
+	if allowWeak {
+		return nil
+	}
+
+	minScore, err := getMinStrength(cmd)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range passwords {
+		if err := password.Check(p, minScore, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generatePassphrase generates a password for encrypt --generate-password
+// and prints it to stderr, the only place it is shown.
+func generatePassphrase() ([]byte, error) {
+	generated, err := password.GeneratePassphrase(password.DefaultWords, password.DefaultSeparator)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "Generated password: %s\nEntropy: %.1f bits, the password is not shown again\n", generated.Password, generated.Entropy)
+	return generated.Password, nil
+}
+
+// reportStrength prints the strength of a password typed at the prompt.
+func reportStrength(p []byte, params *crypto.KDFParams) {
+	fmt.Fprintf(os.Stderr, "Password %s\n", password.Estimate(p).Report(params))
+}
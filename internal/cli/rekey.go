@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DimaKropachev/cryptool/internal/app"
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/password"
+	"github.com/spf13/cobra"
+)
+
+// rekeyCmd represents the rekey command
+var rekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Change the passwords and recipients of an encrypted file",
+	Long: `Changes the key slots of a file without re-encrypting it. The file is unlocked
+with a password or --identity and only its header changes, the file is copied
+with the new header and replaces the old one in a single rename. Without a password
+flag, ` + password.EnvVar + ` or an identity the password is asked for on the terminal,
+and so is the new one for --change-password. For example:
+
+  cryptool rekey --change-password secret.txt.crpt
+  cryptool rekey --password-file old.txt --new-password-file new.txt secret.txt.crpt
+  cryptool rekey --identity me.key --add-recipient crpt-pk-... --remove-slot 1 secret.txt.crpt`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "a file to rekey is required")
+			os.Exit(1)
+		}
+
+		// flag "identity"
+		identities, err := cmd.Flags().GetStringArray("identity")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flags "add-password-slot", "add-password-slot-file", "add-password-slot-fd"
+		addPasswordSlots, err := getSlotPasswords(cmd, "add-password-slot")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "add-recipient"
+		addRecipients, err := cmd.Flags().GetStringArray("add-recipient")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "remove-slot"
+		removeSlots, err := cmd.Flags().GetIntSlice("remove-slot")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "kdf-limit"
+		kdfLimit, err := cmd.Flags().GetUint32("kdf-limit")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flags "kdf", "kdf-time", "kdf-memory", "kdf-threads"
+		kdf, err := getKDFParams(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flags "password", "password-file", "password-fd"
+		// without an identity the password comes from the environment or a prompt
+		var password []byte
+		if passwordGiven(cmd) || len(identities) == 0 {
+			password, err = getPassword(cmd, false)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+
+		// flags "change-password", "new-password", "new-password-file", "new-password-fd"
+		changePassword, err := cmd.Flags().GetBool("change-password")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		var newPassword []byte
+		if changePassword || newPasswordGiven(cmd) {
+			newPassword, err = getNewPassword(cmd)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if !newPasswordGiven(cmd) {
+				reportStrength(newPassword, kdf)
+			}
+		}
+
+		// flags "min-strength" and "allow-weak"
+		passwords := addPasswordSlots
+		if newPassword != nil {
+			passwords = append([][]byte{newPassword}, addPasswordSlots...)
+		}
+		if err := checkPasswords(cmd, kdf, passwords...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		err = app.Rekey(args[0], app.RekeyOptions{
+			Password:         password,
+			Identities:       identities,
+			NewPassword:      newPassword,
+			AddPasswordSlots: addPasswordSlots,
+			AddRecipients:    addRecipients,
+			RemoveSlots:      removeSlots,
+			KDF:              kdf,
+			KDFLimit:         kdfLimit,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rekeyCmd)
+
+	addPasswordFlags(rekeyCmd)
+	rekeyCmd.Flags().StringArray("identity", nil, "identity file that opens one of the slots, can be repeated")
+	addNewPasswordFlags(rekeyCmd)
+	addSlotPasswordFlags(rekeyCmd, "add-password-slot", "add a password slot, can be repeated")
+	rekeyCmd.Flags().StringArray("add-recipient", nil, "add a public key slot, can be repeated")
+	rekeyCmd.Flags().IntSlice("remove-slot", nil, "remove key slots by index")
+	rekeyCmd.Flags().String("kdf", crypto.KDFNamePBKDF2, "key derivation function of new password slots")
+	rekeyCmd.Flags().Uint32("kdf-time", 0, "pbkdf2 iterations or argon2id passes")
+	rekeyCmd.Flags().Uint32("kdf-memory", 0, "argon2id memory or scrypt N, in KiB")
+	rekeyCmd.Flags().Uint8("kdf-threads", 0, "argon2id threads or scrypt parallelization")
+	rekeyCmd.Flags().Int("min-strength", password.DefaultMinScore, "lowest accepted strength of new passwords from 0 (very weak) to 4 (very strong), default from the config file")
+	rekeyCmd.Flags().Bool("allow-weak", false, "accept new passwords below --min-strength")
+	rekeyCmd.Flags().Uint32("kdf-limit", 1, "multiplies the key derivation cost a file may ask for, raise it only for trusted files")
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/DimaKropachev/cryptool/internal/app"
+	"github.com/spf13/cobra"
+)
+
+const (
+	exitWrongKey  = 2
+	exitCorrupted = 3
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the integrity of an encrypted file without writing the plaintext",
+	Long: `Authenticates the header and every chunk of a .crpt file and discards the
+plaintext. The exit code tells what went wrong:
+
+  0  the file is intact
+  1  the file could not be checked
+  2  wrong password, key file or identity
+  3  the file is corrupted, truncated or modified`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "a file to verify is required")
+			os.Exit(1)
+		}
+
+		// flag "keyfile"
+		keyfile, err := cmd.Flags().GetString("keyfile")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "jobs"
+		jobs, err := cmd.Flags().GetInt("jobs")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "identity"
+		identities, err := cmd.Flags().GetStringArray("identity")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flag "kdf-limit"
+		kdfLimit, err := cmd.Flags().GetUint32("kdf-limit")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// flags "password", "password-file", "password-fd"
+		// without a key file or identity the password comes from the environment or a prompt
+		var password []byte
+		if passwordGiven(cmd) || (keyfile == "" && len(identities) == 0) {
+			password, err = getPassword(cmd, false)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+
+		err = app.Verify(cmd.Context(), args[0], app.DecryptOptions{
+			Password:   password,
+			Keyfile:    keyfile,
+			Identities: identities,
+			Jobs:       jobs,
+			KDFLimit:   kdfLimit,
+		})
+		switch {
+		case err == nil:
+		case errors.Is(err, app.ErrWrongKey):
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitWrongKey)
+		case errors.Is(err, app.ErrCorrupted):
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitCorrupted)
+		default:
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	addPasswordFlags(verifyCmd)
+	verifyCmd.Flags().String("keyfile", "", "key file written by encrypt --keyfile-out")
+	verifyCmd.Flags().IntP("jobs", "j", 0, "number of chunks decrypted in parallel (default GOMAXPROCS)")
+	verifyCmd.Flags().StringArray("identity", nil, "identity file written by keygen, can be repeated")
+	verifyCmd.Flags().Uint32("kdf-limit", 1, "multiplies the key derivation cost a file may ask for, raise it only for trusted files")
+}
@@ -0,0 +1,22 @@
+// Package testutil holds helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// CheckGoroutines waits for the goroutines started after before was taken
+// to exit.
+func CheckGoroutines(t testing.TB, name string, before int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("[%s] get %d goroutines, expected: %d", name, runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,256 @@
+// Package archive packs a directory tree into a tar stream and restores it.
+// Paths, permissions, modification times and symlinks are kept. Owners are
+// not, and symlinks get the time they are restored at.
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DimaKropachev/cryptool/pkg/models"
+)
+
+// rootName is the entry of the archived directory itself.
+const rootName = "./"
+
+// Write packs root, the info of the archived directory, and files with the
+// names file.ReadDirectory gives them into a tar stream.
+func Write(ctx context.Context, w io.Writer, root os.FileInfo, files []*models.File) error {
+	tw := tar.NewWriter(w)
+
+	if err := writeHeader(tw, rootName, root, ""); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(f.Name)
+		mode := f.Info.Mode()
+
+		switch {
+		case mode.IsDir():
+			if err := writeHeader(tw, name+"/", f.Info, ""); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(f.Path)
+			if err != nil {
+				return fmt.Errorf("error reading the symlink %s: %w", f.Path, err)
+			}
+			if err := writeHeader(tw, name, f.Info, link); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			if err := writeHeader(tw, name, f.Info, ""); err != nil {
+				return err
+			}
+			if err := copyFile(tw, f); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: %s", ErrUnsupportedFile, f.Path)
+		}
+	}
+
+	return tw.Close()
+}
+
+func writeHeader(tw *tar.Writer, name string, info os.FileInfo, link string) error {
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("error archiving %s: %w", name, err)
+	}
+
+	header.Name = name
+	// PAX keeps long names and sub-second modification times
+	header.Format = tar.FormatPAX
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+	header.AccessTime, header.ChangeTime = time.Time{}, time.Time{}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("error archiving %s: %w", name, err)
+	}
+	return nil
+}
+
+// copyFile writes exactly the size the header of f was written with.
+func copyFile(tw *tar.Writer, f *models.File) error {
+	in, err := os.Open(f.Path)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", f.Path, err)
+	}
+	defer in.Close()
+
+	_, err = io.CopyN(tw, in, f.Info.Size())
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %s", ErrFileChanged, f.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("error archiving %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// Extract restores the tar stream of r into dir, which must exist. Entries
+// are only created inside dir: files are never opened through a symlink, as
+// symlinks are created after everything else, and a symlink behind another
+// symlink is refused. Everything after the end of the archive is read and
+// discarded.
+func Extract(ctx context.Context, r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+
+	var dirs []*tar.Header
+	var links []*tar.Header
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading the archive: %w", err)
+		}
+
+		target, err := entryPath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return fmt.Errorf("error creating the directory %s: %w", target, err)
+			}
+			dirs = append(dirs, header)
+		case tar.TypeReg:
+			if err := extractFile(tr, target, header); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			links = append(links, header)
+		default:
+			return fmt.Errorf("%w: %s", ErrUnsupportedEntry, header.Name)
+		}
+	}
+
+	for _, header := range links {
+		target, _ := entryPath(dir, header.Name)
+		// an earlier symlink of the archive may point outside of dir
+		if err := checkParents(dir, target); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("error creating the directory %s: %w", filepath.Dir(target), err)
+		}
+		if err := os.Symlink(header.Linkname, target); err != nil {
+			return fmt.Errorf("error creating the symlink %s: %w", target, err)
+		}
+	}
+
+	// creating the entries changes the times of their directories, and a
+	// read-only directory cannot be filled, so directories come last, deepest
+	// first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		target, _ := entryPath(dir, dirs[i].Name)
+		if err := restoreMetadata(target, dirs[i]); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return fmt.Errorf("error reading the archive: %w", err)
+	}
+	return nil
+}
+
+// entryPath returns the path of the entry name inside dir.
+func entryPath(dir, name string) (string, error) {
+	name = path.Clean(name)
+	if name == "." {
+		return dir, nil
+	}
+
+	local := filepath.FromSlash(name)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return filepath.Join(dir, local), nil
+}
+
+// checkParents fails if a directory between dir and target is a symlink.
+// Missing directories are fine, they are created as real directories.
+func checkParents(dir, target string) error {
+	rel, err := filepath.Rel(dir, filepath.Dir(target))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsafePath, target)
+	}
+	if rel == "." {
+		return nil
+	}
+
+	current := dir
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, name)
+
+		info, err := os.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading the directory %s: %w", current, err)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s is behind the symlink %s", ErrUnsafePath, target, current)
+		}
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, target string, header *tar.Header) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("error creating the directory %s: %w", filepath.Dir(target), err)
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating the file %s: %w", target, err)
+	}
+
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("error writing the file %s: %w", target, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("error writing the file %s: %w", target, err)
+	}
+
+	return restoreMetadata(target, header)
+}
+
+// restoreMetadata sets the permissions and the modification time of header.
+// Owners and setuid, setgid and sticky bits are not restored.
+func restoreMetadata(target string, header *tar.Header) error {
+	if err := os.Chmod(target, header.FileInfo().Mode().Perm()); err != nil {
+		return fmt.Errorf("error restoring the mode of %s: %w", target, err)
+	}
+	if err := os.Chtimes(target, header.ModTime, header.ModTime); err != nil {
+		return fmt.Errorf("error restoring the time of %s: %w", target, err)
+	}
+	return nil
+}
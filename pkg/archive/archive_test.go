@@ -0,0 +1,214 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/DimaKropachev/cryptool/pkg/file"
+)
+
+func TestWriteExtract(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 789000000, time.UTC)
+
+	mustMkdir(t, filepath.Join(src, "a", "b"))
+	mustMkdir(t, filepath.Join(src, "empty"))
+	mustWrite(t, filepath.Join(src, "a", "b", "x.txt"), "hello", 0644)
+	mustWrite(t, filepath.Join(src, "a", "secret"), "secret", 0600)
+	if err := os.Chtimes(filepath.Join(src, "a", "b", "x.txt"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(src, "empty"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	symlinks := runtime.GOOS != "windows"
+	if symlinks {
+		if err := os.Symlink(filepath.Join("b", "x.txt"), filepath.Join(src, "a", "link")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := file.ReadDirectory(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(context.Background(), &buf, root, files); err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+
+	dst := t.TempDir()
+	if err := Extract(context.Background(), &buf, dst); err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+
+	for _, f := range files {
+		want, got := f.Info, lstat(t, filepath.Join(dst, f.Name))
+
+		if got.Mode().Type() != want.Mode().Type() {
+			t.Fatalf("[%s] get type: %v, expected type: %v", f.Name, got.Mode().Type(), want.Mode().Type())
+		}
+		if runtime.GOOS != "windows" && got.Mode().Perm() != want.Mode().Perm() {
+			t.Fatalf("[%s] get mode: %v, expected mode: %v", f.Name, got.Mode().Perm(), want.Mode().Perm())
+		}
+		if got.Mode()&os.ModeSymlink == 0 && !got.ModTime().Equal(want.ModTime()) {
+			t.Fatalf("[%s] get time: %v, expected time: %v", f.Name, got.ModTime(), want.ModTime())
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "a", "b", "x.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("get content: %q (%v), expected content: %q", data, err, "hello")
+	}
+
+	if symlinks {
+		link, err := os.Readlink(filepath.Join(dst, "a", "link"))
+		if err != nil || link != filepath.Join("b", "x.txt") {
+			t.Fatalf("get link: %q (%v), expected link: %q", link, err, filepath.Join("b", "x.txt"))
+		}
+	}
+}
+
+func TestExtractUnsafe(t *testing.T) {
+	type entry struct {
+		name string
+		typ  byte
+		link string
+	}
+
+	type Case struct {
+		name    string
+		entries []entry
+		err     error
+	}
+
+	cases := []Case{
+		{name: "parent", entries: []entry{{name: "../evil", typ: tar.TypeReg}}, err: ErrUnsafePath},
+		{name: "nested parent", entries: []entry{{name: "a/../../evil", typ: tar.TypeReg}}, err: ErrUnsafePath},
+		{name: "absolute", entries: []entry{{name: "/evil", typ: tar.TypeReg}}, err: ErrUnsafePath},
+		{name: "device", entries: []entry{{name: "dev", typ: tar.TypeChar}}, err: ErrUnsupportedEntry},
+		{name: "hard link", entries: []entry{{name: "hard", typ: tar.TypeLink, link: "/etc/passwd"}}, err: ErrUnsupportedEntry},
+	}
+
+	if runtime.GOOS != "windows" {
+		// a file behind a symlink to outside would be written outside
+		cases = append(cases, Case{
+			name: "file behind symlink",
+			entries: []entry{
+				{name: "link", typ: tar.TypeSymlink, link: t.TempDir()},
+				{name: "link/evil", typ: tar.TypeReg},
+			},
+		}, Case{
+			name: "symlink behind symlink",
+			entries: []entry{
+				{name: "link", typ: tar.TypeSymlink, link: t.TempDir()},
+				{name: "link/evil", typ: tar.TypeSymlink, link: "x"},
+			},
+			err: ErrUnsafePath,
+		}, Case{
+			name: "symlink behind nested symlink",
+			entries: []entry{
+				{name: "a/link", typ: tar.TypeSymlink, link: t.TempDir()},
+				{name: "a/link/b/evil", typ: tar.TypeSymlink, link: "x"},
+			},
+			err: ErrUnsafePath,
+		})
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, e := range c.entries {
+			if err := tw.WriteHeader(&tar.Header{Name: e.name, Typeflag: e.typ, Linkname: e.link, Mode: 0644}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		dst := filepath.Join(t.TempDir(), "dst")
+		mustMkdir(t, dst)
+
+		err := Extract(context.Background(), &buf, dst)
+		if c.err == nil {
+			if err == nil {
+				t.Fatalf("[%s] get error: %v, expected an error", c.name, err)
+			}
+		} else if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+
+		if _, err := os.Lstat(filepath.Join(filepath.Dir(dst), "evil")); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("[%s] file written outside of the output directory", c.name)
+		}
+		for _, e := range c.entries {
+			if e.typ == tar.TypeSymlink {
+				if _, err := os.Lstat(filepath.Join(e.link, "evil")); !errors.Is(err, os.ErrNotExist) {
+					t.Fatalf("[%s] file written through a symlink", c.name)
+				}
+			}
+		}
+	}
+}
+
+func TestWriteCancelled(t *testing.T) {
+	src := t.TempDir()
+	mustWrite(t, filepath.Join(src, "x.txt"), "hello", 0644)
+
+	root, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := file.ReadDirectory(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	if err := Write(ctx, &buf, root, files); !errors.Is(err, context.Canceled) {
+		t.Fatalf("get error: %v, expected error: %v", err, context.Canceled)
+	}
+}
+
+func mustMkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustWrite(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lstat(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
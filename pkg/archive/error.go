@@ -0,0 +1,10 @@
+package archive
+
+import "errors"
+
+var (
+	ErrUnsupportedFile  = errors.New("only regular files, directories and symlinks can be archived")
+	ErrFileChanged      = errors.New("file changed while it was archived")
+	ErrUnsafePath       = errors.New("archive entry path leaves the output directory")
+	ErrUnsupportedEntry = errors.New("unsupported archive entry type")
+)
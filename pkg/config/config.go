@@ -0,0 +1,121 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+)
+
+const (
+	dirName  = "cryptool"
+	fileName = "config.json"
+)
+
+type KDFParams struct {
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+type Config struct {
+	// DefaultKDF is used by encrypt when --kdf is not given
+	DefaultKDF string `json:"default_kdf,omitempty"`
+	// KDF holds the calibrated parameters by KDF name
+	KDF map[string]KDFParams `json:"kdf,omitempty"`
+	// MinPasswordStrength is the lowest password score (0 to 4) encrypt
+	// accepts when --min-strength is not given
+	MinPasswordStrength *int `json:"min_password_strength,omitempty"`
+
+	path string
+}
+
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating the config directory: %w", err)
+	}
+
+	return filepath.Join(dir, dirName, fileName), nil
+}
+
+// Load reads the user config. A missing config file is not an error.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadFrom(path)
+}
+
+func LoadFrom(path string) (*Config, error) {
+	cfg := &Config{
+		KDF:  map[string]KDFParams{},
+		path: path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("error reading config \"%s\": %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config \"%s\": %w", path, err)
+	}
+	if cfg.KDF == nil {
+		cfg.KDF = map[string]KDFParams{}
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("error creating config directory: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing config \"%s\": %w", c.path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) GetPath() string {
+	return c.path
+}
+
+// KDFParams returns the saved parameters of the KDF, or nil if it was not calibrated.
+func (c *Config) KDFParams(kdf crypto.KDF) *crypto.KDFParams {
+	params, ok := c.KDF[kdf.String()]
+	if !ok {
+		return nil
+	}
+
+	return &crypto.KDFParams{
+		KDF:     kdf,
+		Time:    params.Time,
+		Memory:  params.Memory,
+		Threads: params.Threads,
+	}
+}
+
+func (c *Config) SetKDFParams(params *crypto.KDFParams) {
+	c.KDF[params.KDF.String()] = KDFParams{
+		Time:    params.Time,
+		Memory:  params.Memory,
+		Threads: params.Threads,
+	}
+}
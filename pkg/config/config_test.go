@@ -0,0 +1,39 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+)
+
+func TestConfigSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), dirName, fileName)
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+	if params := cfg.KDFParams(crypto.KDFArgon2id); params != nil {
+		t.Fatalf("get params: %s, expected params: %v", params, nil)
+	}
+
+	want := &crypto.KDFParams{KDF: crypto.KDFArgon2id, Time: 4, Memory: 128 * 1024, Threads: 2}
+	cfg.SetKDFParams(want)
+	cfg.DefaultKDF = crypto.KDFNameArgon2id
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = LoadFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DefaultKDF != crypto.KDFNameArgon2id {
+		t.Fatalf("get default kdf: %s, expected: %s", cfg.DefaultKDF, crypto.KDFNameArgon2id)
+	}
+	if params := cfg.KDFParams(crypto.KDFArgon2id); !reflect.DeepEqual(params, want) {
+		t.Fatalf("get params: %s, expected params: %s", params, want)
+	}
+}
@@ -1,11 +1,8 @@
 package aes
 
 import (
-	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-
-	"github.com/DimaKropachev/cryptool/pkg/crypto"
 )
 
 type AESGCM struct {
@@ -14,7 +11,7 @@ type AESGCM struct {
 	TagSize   int
 }
 
-func NewAESGCM(key, salt []byte) (*AESGCM, error) {
+func NewAESGCM(key []byte) (*AESGCM, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -34,28 +31,14 @@ func NewAESGCM(key, salt []byte) (*AESGCM, error) {
 	return aes, nil
 }
 
-func (aes *AESGCM) Encrypt(plaintext []byte) ([]byte, error) {
-	result := bytes.NewBuffer([]byte{})
-
-	// generate nonce
-	nonce := crypto.GenerateNonce(aes.NonceSize)
-
-	if _, err := result.Write(nonce); err != nil {
-		return nil, err
-	}
-
-	// encipher plaintext
-	ciphertext := aes.gcm.Seal(nil, nonce, plaintext, nil)
-
-	if _, err := result.Write(ciphertext); err != nil {
-		return nil, err
-	}
+func (aes *AESGCM) Encrypt(dst, plaintext, nonce, additionalData []byte) ([]byte, error) {
+	ciphertext := aes.gcm.Seal(dst, nonce, plaintext, additionalData)
 
-	return result.Bytes(), nil
+	return ciphertext, nil
 }
 
-func (aes *AESGCM) Decrypt(ciphertext, nonce []byte) ([]byte, error) {
-	plaintext, err := aes.gcm.Open(nil, nonce, ciphertext, nil)
+func (aes *AESGCM) Decrypt(dst, ciphertext, nonce, additionalData []byte) ([]byte, error) {
+	plaintext, err := aes.gcm.Open(dst, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,9 @@
 package algorithms
 
 import (
-	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"errors"
+	"fmt"
+
 	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms/aes"
 	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms/chacha20"
 )
@@ -18,85 +20,79 @@ const (
 	AlgCHACHA20POLY1305 = "chacha20-poly1305"
 )
 
+var (
+	ErrUnknownAlgorithm = errors.New("unknown algorithm")
+	ErrInvalidKeySize   = errors.New("invalid key size")
+)
+
+var algorithmIDs = map[string]int{
+	AlgAES128GCM:        IDAES128GCM,
+	AlgAES192GCM:        IDAES192GCM,
+	AlgAES256GCM:        IDAES256GCM,
+	AlgCHACHA20POLY1305: IDCHACHA20POLY1305,
+}
+
+var keySizes = map[int]int{
+	IDAES128GCM:        16,
+	IDAES192GCM:        24,
+	IDAES256GCM:        32,
+	IDCHACHA20POLY1305: 32,
+}
+
+// CipherAlgorithm is an AEAD. Like cipher.AEAD, Encrypt and Decrypt append
+// their result to dst, so a buffer with enough capacity is reused.
 type CipherAlgorithm interface {
-	Encrypt(plaintext []byte) ([]byte, error)
-	Decrypt(ciphertext, nonce []byte) ([]byte, error)
+	Encrypt(dst, plaintext, nonce, additionalData []byte) ([]byte, error)
+	Decrypt(dst, ciphertext, nonce, additionalData []byte) ([]byte, error)
 	GetNonceSize() int
 	GetTagSize() int
 }
 
-func CreateAlgorithmByName(algorithm string, password, salt []byte) (CipherAlgorithm, int, error) {
-	var (
-		alg CipherAlgorithm
-		id  int
-		err error
-	)
-
-	if len(password) != 0 {
-		switch algorithm {
-		case AlgAES128GCM:
-			id = IDAES128GCM
-			alg, err = aes.NewAESGCM(crypto.GenerateKeyFromPassword(password, salt, 16), salt)
-		case AlgAES192GCM:
-			id = IDAES192GCM
-			alg, err = aes.NewAESGCM(crypto.GenerateKeyFromPassword(password, salt, 24), salt)
-		case AlgAES256GCM:
-			id = IDAES256GCM
-			alg, err = aes.NewAESGCM(crypto.GenerateKeyFromPassword(password, salt, 32), salt)
-		case AlgCHACHA20POLY1305:
-			id = IDCHACHA20POLY1305
-			alg, err = chacha20.NewChaCha20Poly1305(crypto.GenerateKeyFromPassword(password, salt, 32), salt)
-		}
-		if err != nil {
-			return nil, id, err
-		}
-	} else {
-		// TODO: что делать если пользователь не ввел пароль
-		switch algorithm {
-		case AlgAES128GCM:
-			id = IDAES128GCM
-			alg, err = aes.NewAESGCM(crypto.GenerateKey(16), salt)
-		case AlgAES192GCM:
-			id = IDAES192GCM
-			alg, err = aes.NewAESGCM(crypto.GenerateKey(24), salt)
-		case AlgAES256GCM:
-			id = IDAES256GCM
-			alg, err = aes.NewAESGCM(crypto.GenerateKey(32), salt)
-		case AlgCHACHA20POLY1305:
-			id = IDCHACHA20POLY1305
-			alg, err = chacha20.NewChaCha20Poly1305(crypto.GenerateKey(32), salt)
-		}
-		if err != nil {
-			return nil, id, err
+func IDByName(algorithm string) (int, error) {
+	id, ok := algorithmIDs[algorithm]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, algorithm)
+	}
+	return id, nil
+}
+
+func NameByID(algorithm int) (string, error) {
+	for name, id := range algorithmIDs {
+		if id == algorithm {
+			return name, nil
 		}
 	}
+	return "", fmt.Errorf("%w: id %d", ErrUnknownAlgorithm, algorithm)
+}
 
-	return alg, id, nil
+func KeySize(algorithm int) (int, error) {
+	keySize, ok := keySizes[algorithm]
+	if !ok {
+		return 0, fmt.Errorf("%w: id %d", ErrUnknownAlgorithm, algorithm)
+	}
+	return keySize, nil
 }
 
-func CreateAlgorithmByID(algorithm int, password, salt []byte) (CipherAlgorithm, error) {
-	var (
-		alg CipherAlgorithm
-		err error
-	)
-
-	if len(password) != 0 {
-		switch algorithm {
-		case IDAES128GCM:
-			alg, err = aes.NewAESGCM(crypto.GenerateKeyFromPassword(password, salt, 16), salt)
-		case IDAES192GCM:
-			alg, err = aes.NewAESGCM(crypto.GenerateKeyFromPassword(password, salt, 24), salt)
-		case IDAES256GCM:
-			alg, err = aes.NewAESGCM(crypto.GenerateKeyFromPassword(password, salt, 32), salt)
-		case IDCHACHA20POLY1305:
-			alg, err = chacha20.NewChaCha20Poly1305(crypto.GenerateKeyFromPassword(password, salt, 32), salt)
-		}
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		// TODO: что делать если пользователь не ввел пароль
+func CreateAlgorithmByID(algorithm int, key []byte) (CipherAlgorithm, error) {
+	keySize, err := KeySize(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != keySize {
+		return nil, fmt.Errorf("%w: %d bytes, expected %d", ErrInvalidKeySize, len(key), keySize)
+	}
+
+	return newAlgorithm(algorithm, key)
+}
+
+func newAlgorithm(id int, key []byte) (CipherAlgorithm, error) {
+	switch id {
+	case IDAES128GCM, IDAES192GCM, IDAES256GCM:
+		return aes.NewAESGCM(key)
+	case IDCHACHA20POLY1305:
+		return chacha20.NewChaCha20Poly1305(key)
 	}
 
-	return alg, nil
+	return nil, fmt.Errorf("%w: id %d", ErrUnknownAlgorithm, id)
 }
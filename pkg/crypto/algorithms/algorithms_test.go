@@ -0,0 +1,42 @@
+package algorithms
+
+import (
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+)
+
+func TestDecryptAdditionalData(t *testing.T) {
+	algs := []string{AlgAES128GCM, AlgAES192GCM, AlgAES256GCM, AlgCHACHA20POLY1305}
+
+	for _, name := range algs {
+		id, err := IDByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keySize, err := KeySize(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		alg, err := CreateAlgorithmByID(id, crypto.GenerateKey(keySize))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		nonce := crypto.GenerateNonce(alg.GetNonceSize())
+		ciphertext, err := alg.Encrypt(nil, []byte("secret data"), nonce, []byte("header"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := alg.Decrypt(nil, ciphertext, nonce, []byte("header")); err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", name, err, nil)
+		}
+		if _, err := alg.Decrypt(nil, ciphertext, nonce, []byte("Header")); err == nil {
+			t.Fatalf("[%s] chunk opened with modified associated data", name)
+		}
+		if _, err := alg.Decrypt(nil, ciphertext, nonce, nil); err == nil {
+			t.Fatalf("[%s] chunk opened without associated data", name)
+		}
+	}
+}
@@ -1,10 +1,8 @@
 package chacha20
 
 import (
-	"bytes"
 	"crypto/cipher"
 
-	"github.com/DimaKropachev/cryptool/pkg/crypto"
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
@@ -14,7 +12,7 @@ type ChaCha20Poly1305 struct {
 	TagSize   int
 }
 
-func NewChaCha20Poly1305(key, salt []byte) (*ChaCha20Poly1305, error) {
+func NewChaCha20Poly1305(key []byte) (*ChaCha20Poly1305, error) {
 	aead, err := chacha20poly1305.NewX(key)
 	if err != nil {
 		return nil, err
@@ -27,26 +25,14 @@ func NewChaCha20Poly1305(key, salt []byte) (*ChaCha20Poly1305, error) {
 	}, nil
 }
 
-func (chacha20 *ChaCha20Poly1305) Encrypt(plaintext []byte) ([]byte, error) {
-	result := bytes.NewBuffer([]byte{})
+func (chacha20 *ChaCha20Poly1305) Encrypt(dst, plaintext, nonce, additionalData []byte) ([]byte, error) {
+	ciphertext := chacha20.aead.Seal(dst, nonce, plaintext, additionalData)
 
-	nonce := crypto.GenerateNonce(chacha20.NonceSize)
-
-	if _, err := result.Write(nonce); err != nil {
-		return nil, err
-	}
-
-	ciphertext := chacha20.aead.Seal(nil, nonce, plaintext, nil)
-
-	if _, err := result.Write(ciphertext); err != nil {
-		return nil, err
-	}
-
-	return result.Bytes(), nil
+	return ciphertext, nil
 }
 
-func (chacha20 *ChaCha20Poly1305) Decrypt(ciphertext, nonce []byte) ([]byte, error) {
-	plaintext, err := chacha20.aead.Open(nil, nonce, ciphertext, nil)
+func (chacha20 *ChaCha20Poly1305) Decrypt(dst, ciphertext, nonce, additionalData []byte) ([]byte, error) {
+	plaintext, err := chacha20.aead.Open(dst, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, err
 	}
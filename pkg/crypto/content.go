@@ -0,0 +1,53 @@
+package crypto
+
+import "fmt"
+
+// ContentType tells what the plaintext of a file is.
+type ContentType uint8
+
+const (
+	// ContentFile is the content of a single file or stream.
+	ContentFile ContentType = 0
+	// ContentArchive is a tar archive of a directory tree.
+	ContentArchive ContentType = 1
+)
+
+func (c ContentType) String() string {
+	switch c {
+	case ContentFile:
+		return "file"
+	case ContentArchive:
+		return "archive"
+	}
+	return fmt.Sprintf("content type(%d)", uint8(c))
+}
+
+// ContentType returns the type of the plaintext. Headers without the content
+// type extension hold a single file.
+func (h *Header) ContentType() (ContentType, error) {
+	value, ok := h.Extension(ExtensionContentType)
+	if !ok {
+		return ContentFile, nil
+	}
+
+	if len(value) != 1 {
+		return 0, fmt.Errorf("%w: content type", ErrInvalidExtension)
+	}
+
+	content := ContentType(value[0])
+	switch content {
+	case ContentFile, ContentArchive:
+		return content, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownContentType, content)
+}
+
+// SetContentType marks the plaintext type. Single files keep the header
+// without the extension, so older versions read them.
+func (h *Header) SetContentType(content ContentType) {
+	if content == ContentFile {
+		return
+	}
+	// critical, an older version would write the archive as a single file
+	h.SetExtension(ExtensionContentType, true, []byte{byte(content)})
+}
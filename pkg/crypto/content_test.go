@@ -0,0 +1,31 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHeaderContentType(t *testing.T) {
+	header := newTestHeader(Version2)
+
+	content, err := header.ContentType()
+	if err != nil || content != ContentFile {
+		t.Fatalf("get content type: %s (%v), expected: %s", content, err, ContentFile)
+	}
+
+	header.SetContentType(ContentFile)
+	if _, ok := header.Extension(ExtensionContentType); ok {
+		t.Fatalf("content type extension set for a single file")
+	}
+
+	header.SetContentType(ContentArchive)
+	content, err = header.ContentType()
+	if err != nil || content != ContentArchive {
+		t.Fatalf("get content type: %s (%v), expected: %s", content, err, ContentArchive)
+	}
+
+	header.SetExtension(ExtensionContentType, true, []byte{42})
+	if _, err := header.ContentType(); !errors.Is(err, ErrUnknownContentType) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrUnknownContentType)
+	}
+}
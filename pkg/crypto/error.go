@@ -0,0 +1,31 @@
+package crypto
+
+import "errors"
+
+var (
+	ErrInvalidMagicNum    = errors.New("file is not encrypted by cryptool")
+	ErrUnsupportedVersion = errors.New("unsupported format version")
+	ErrInvalidNonceSize   = errors.New("invalid nonce size")
+	ErrInvalidSaltSize    = errors.New("invalid salt size")
+	ErrInvalidBlockSize   = errors.New("invalid block size")
+
+	ErrInvalidExtension         = errors.New("invalid header extension")
+	ErrUnknownCriticalExtension = errors.New("unknown critical header extension")
+
+	ErrUnknownKDF       = errors.New("unknown key derivation function")
+	ErrInvalidKDFParams = errors.New("invalid key derivation parameters")
+	ErrKDFLimit         = errors.New("key derivation is costlier than allowed, use --kdf-limit to raise the limit for a trusted file")
+
+	ErrUnknownKeyMode   = errors.New("unknown key mode")
+	ErrUnknownKeyFormat = errors.New("unknown key file format")
+	ErrInvalidKeyFile   = errors.New("invalid key file")
+
+	ErrUnknownContentType = errors.New("unknown content type")
+
+	ErrInvalidRecipient = errors.New("invalid recipient")
+	ErrInvalidIdentity  = errors.New("invalid identity")
+	ErrSlotMismatch     = errors.New("key slot cannot be opened with this key")
+	ErrNoMatchingSlot   = errors.New("no key slot can be opened with the given password or identities")
+
+	ErrHeaderAuthentication = errors.New("header authentication failed")
+)
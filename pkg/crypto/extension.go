@@ -0,0 +1,160 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// ExtensionType identifies a header extension. Extensions are stored as
+// type-length-value records: type (uint16), length (uint32), value. The high
+// bit of the type marks a critical extension, which readers must understand
+// to decrypt the file. Unknown optional extensions are skipped.
+type ExtensionType uint16
+
+const (
+	extensionEnd      ExtensionType = 0
+	extensionCritical ExtensionType = 0x8000
+
+	maxExtensionSize = 1 << 20
+	// type and length in front of every extension value
+	extensionRecordSize = 6
+)
+
+const (
+	// ExtensionKeyMode tells whether the key comes from a password or a key file.
+	ExtensionKeyMode ExtensionType = 2
+	// ExtensionKeySlots holds the file key wrapped for every way of unlocking the file.
+	ExtensionKeySlots ExtensionType = 3
+	// ExtensionContentType tells whether the plaintext is a file or an archive.
+	ExtensionContentType ExtensionType = 4
+	// ExtensionPadding fills the space reserved for the key slots.
+	ExtensionPadding ExtensionType = 5
+)
+
+// knownExtensions lists the extension types this version understands.
+var knownExtensions = map[ExtensionType]bool{
+	ExtensionKeyMode:     true,
+	ExtensionKeySlots:    true,
+	ExtensionContentType: true,
+	ExtensionPadding:     true,
+}
+
+func (t ExtensionType) String() string {
+	switch t {
+	case ExtensionKeyMode:
+		return "key mode"
+	case ExtensionKeySlots:
+		return "key slots"
+	case ExtensionContentType:
+		return "content type"
+	case ExtensionPadding:
+		return "padding"
+	}
+	return fmt.Sprintf("extension(%d)", uint16(t))
+}
+
+type Extension struct {
+	Type     ExtensionType
+	Critical bool
+	Value    []byte
+}
+
+// Extension returns the value of the extension with the given type.
+func (h *Header) Extension(t ExtensionType) ([]byte, bool) {
+	for _, ext := range h.Extensions {
+		if ext.Type == t {
+			return ext.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetExtension adds the extension or replaces the value of an existing one.
+func (h *Header) SetExtension(t ExtensionType, critical bool, value []byte) {
+	for i, ext := range h.Extensions {
+		if ext.Type == t {
+			h.Extensions[i] = Extension{Type: t, Critical: critical, Value: value}
+			return
+		}
+	}
+	h.Extensions = append(h.Extensions, Extension{Type: t, Critical: critical, Value: value})
+}
+
+// removeExtension removes the extension with the given type.
+func (h *Header) removeExtension(t ExtensionType) {
+	h.Extensions = slices.DeleteFunc(h.Extensions, func(ext Extension) bool {
+		return ext.Type == t
+	})
+}
+
+func decryptExtensions(r io.Reader) ([]Extension, error) {
+	extensions := []Extension{}
+
+	for {
+		var rawType uint16
+		if err := binary.Read(r, binary.LittleEndian, &rawType); err != nil {
+			return nil, err
+		}
+
+		t := ExtensionType(rawType) &^ extensionCritical
+		critical := ExtensionType(rawType)&extensionCritical != 0
+		if t == extensionEnd {
+			return extensions, nil
+		}
+
+		var length uint32
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, err
+		}
+		if length > maxExtensionSize {
+			return nil, fmt.Errorf("%w: type %d is too large", ErrInvalidExtension, t)
+		}
+
+		if critical && !knownExtensions[t] {
+			return nil, fmt.Errorf("%w: type %d", ErrUnknownCriticalExtension, t)
+		}
+
+		// unknown optional extensions are kept, so the header can be
+		// serialized again byte for byte
+		value := make([]byte, length)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, err
+		}
+
+		extensions = append(extensions, Extension{
+			Type:     t,
+			Critical: critical,
+			Value:    value,
+		})
+	}
+}
+
+func encryptExtensions(w *bytes.Buffer, extensions []Extension) error {
+	for _, ext := range extensions {
+		if ext.Type == extensionEnd || ext.Type&extensionCritical != 0 || len(ext.Value) > maxExtensionSize {
+			return fmt.Errorf("%w: type %d", ErrInvalidExtension, ext.Type)
+		}
+
+		rawType := ext.Type
+		if ext.Critical {
+			rawType |= extensionCritical
+		}
+
+		if err := binary.Write(w, binary.LittleEndian, uint16(rawType)); err != nil {
+			return err
+		}
+
+		if err := binary.Write(w, binary.LittleEndian, uint32(len(ext.Value))); err != nil {
+			return err
+		}
+
+		if _, err := w.Write(ext.Value); err != nil {
+			return err
+		}
+	}
+
+	return binary.Write(w, binary.LittleEndian, uint16(extensionEnd))
+}
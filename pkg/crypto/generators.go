@@ -2,13 +2,15 @@ package crypto
 
 import (
 	"crypto/rand"
-
-	"golang.org/x/crypto/pbkdf2"
-	"golang.org/x/crypto/sha3"
 )
 
 const (
 	DefaultSaltSize = 16
+
+	// StreamNonceOverhead is the part of a STREAM nonce taken by the chunk
+	// index (4 bytes) and the chunk flag (1 byte). The rest is a random
+	// prefix stored in the header.
+	StreamNonceOverhead = 5
 )
 
 func GenerateSalt(size int) []byte {
@@ -23,16 +25,8 @@ func GenerateNonce(size int) []byte {
 	return nonce
 }
 
-func GenerateKeyFromPassword(password, salt []byte, keySize int) []byte {
-	key := pbkdf2.Key(
-		password,
-		salt,
-		100000,
-		keySize,
-		sha3.New256,
-	)
-
-	return key
+func GenerateNoncePrefix(nonceSize int) []byte {
+	return GenerateNonce(nonceSize - StreamNonceOverhead)
 }
 
 func GenerateKey(keySize int) []byte {
@@ -9,23 +9,76 @@ import (
 
 const MagicNum = "CRPT"
 
+const (
+	// Version0 is the original layout without a version field. The header
+	// is not authenticated and each chunk carries its own random nonce.
+	Version0 = 0
+	// Version2 seals chunks as a STREAM: nonces are derived from the nonce
+	// prefix, the chunk index and a final-chunk flag. The extension area
+	// follows the nonce prefix. Version 1 was never released and is not read.
+	Version2 = 2
+
+	CurrentVersion = Version2
+
+	// v0 files store AlgID (never zero) right after the magic number, so a
+	// zero byte in its place marks a versioned header.
+	versionMarker = 0
+)
+
+const (
+	// Chunks have a fixed size, so the output does not depend on the machine
+	// and memory use does not depend on the file size.
+	MinBlockSize = 4 << 10
+	MaxBlockSize = 64 << 20
+	// v0 files were sealed as one chunk of the whole file, its size was
+	// capped at 2 GiB.
+	MaxLegacyBlockSize = 2 << 30
+
+	maxSaltSize  = 1 << 10
+	maxNonceSize = 1 << 10
+)
+
+// ValidateBlockSize reports whether a header of the given version may carry
+// blockSize. It is checked before the block size is used to size a buffer. A
+// v0 header is not authenticated and may claim any legacy block size, so
+// buffers of v0 chunks grow with the data read instead of being allocated
+// up front.
+func ValidateBlockSize(version uint8, blockSize uint64) error {
+	if version == Version0 {
+		if blockSize > MaxLegacyBlockSize {
+			return fmt.Errorf("%w: %d bytes, expected at most %d", ErrInvalidBlockSize, blockSize, uint64(MaxLegacyBlockSize))
+		}
+		return nil
+	}
+
+	if blockSize < MinBlockSize || blockSize > MaxBlockSize {
+		return fmt.Errorf("%w: %d bytes, expected %d to %d", ErrInvalidBlockSize, blockSize, MinBlockSize, MaxBlockSize)
+	}
+	return nil
+}
+
 type Header struct {
-	MagicNum  string
-	AlgID     uint16
-	BlockSize uint64
-	SaltSize  uint32
-	Salt      []byte
-	NonceSize uint32
+	MagicNum    string
+	Version     uint8
+	AlgID       uint16
+	BlockSize   uint64
+	SaltSize    uint32
+	Salt        []byte
+	NonceSize   uint32
+	NoncePrefix []byte
+	Extensions  []Extension
 }
 
-func NewHeader(algID, blockSize, saltSize, nonceSize int, salt []byte) *Header {
+func NewHeader(algID, blockSize, saltSize, nonceSize int, salt, noncePrefix []byte) *Header {
 	header := &Header{
-		MagicNum:  MagicNum,
-		AlgID:     uint16(algID),
-		BlockSize: uint64(blockSize),
-		SaltSize:  uint32(saltSize),
-		Salt:      salt,
-		NonceSize: uint32(nonceSize),
+		MagicNum:    MagicNum,
+		Version:     CurrentVersion,
+		AlgID:       uint16(algID),
+		BlockSize:   uint64(blockSize),
+		SaltSize:    uint32(saltSize),
+		Salt:        salt,
+		NonceSize:   uint32(nonceSize),
+		NoncePrefix: noncePrefix,
 	}
 
 	return header
@@ -36,33 +89,58 @@ func DecryptHeader(r io.Reader) (*Header, error) {
 
 	// Decrypt MagicNum
 	magicNum := make([]byte, 4)
-	_, err := r.Read(magicNum)
-	if err != nil {
+	if _, err := io.ReadFull(r, magicNum); err != nil {
 		return nil, err
 	}
 	if MagicNum != string(magicNum) {
-		return nil, fmt.Errorf("")
+		return nil, ErrInvalidMagicNum
 	}
 	header.MagicNum = string(magicNum)
 
-	// Decrypt algID
-	if err := binary.Read(r, binary.LittleEndian, &header.AlgID); err != nil {
+	// Decrypt Version and algID
+	marker := make([]byte, 1)
+	if _, err := io.ReadFull(r, marker); err != nil {
 		return nil, err
 	}
+	if marker[0] == versionMarker {
+		if err := binary.Read(r, binary.LittleEndian, &header.Version); err != nil {
+			return nil, err
+		}
+		if header.Version != CurrentVersion {
+			return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, header.Version)
+		}
+
+		if err := binary.Read(r, binary.LittleEndian, &header.AlgID); err != nil {
+			return nil, err
+		}
+	} else {
+		high := make([]byte, 1)
+		if _, err := io.ReadFull(r, high); err != nil {
+			return nil, err
+		}
+		header.Version = Version0
+		header.AlgID = uint16(marker[0]) | uint16(high[0])<<8
+	}
 
 	// Decrypt BlockSize
 	if err := binary.Read(r, binary.LittleEndian, &header.BlockSize); err != nil {
 		return nil, err
 	}
+	if err := ValidateBlockSize(header.Version, header.BlockSize); err != nil {
+		return nil, err
+	}
 
 	// Drcrypt SaltSize
 	if err := binary.Read(r, binary.LittleEndian, &header.SaltSize); err != nil {
 		return nil, err
 	}
+	if header.SaltSize > maxSaltSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidSaltSize, header.SaltSize)
+	}
 
 	// Decrypt Salt
 	salt := make([]byte, header.SaltSize)
-	if _, err := r.Read(salt); err != nil {
+	if _, err := io.ReadFull(r, salt); err != nil {
 		return nil, err
 	}
 	header.Salt = salt
@@ -72,6 +150,27 @@ func DecryptHeader(r io.Reader) (*Header, error) {
 		return nil, err
 	}
 
+	if header.Version == Version0 {
+		return &header, nil
+	}
+
+	// Decrypt NoncePrefix
+	if header.NonceSize < StreamNonceOverhead || header.NonceSize > maxNonceSize {
+		return nil, ErrInvalidNonceSize
+	}
+	noncePrefix := make([]byte, header.NonceSize-StreamNonceOverhead)
+	if _, err := io.ReadFull(r, noncePrefix); err != nil {
+		return nil, err
+	}
+	header.NoncePrefix = noncePrefix
+
+	// Decrypt Extensions
+	extensions, err := decryptExtensions(r)
+	if err != nil {
+		return nil, err
+	}
+	header.Extensions = extensions
+
 	return &header, nil
 }
 
@@ -82,6 +181,12 @@ func EncryptHeader(header *Header) ([]byte, error) {
 		return nil, err
 	}
 
+	if header.Version != Version0 {
+		if _, err := result.Write([]byte{versionMarker, header.Version}); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := binary.Write(result, binary.LittleEndian, header.AlgID); err != nil {
 		return nil, err
 	}
@@ -102,5 +207,38 @@ func EncryptHeader(header *Header) ([]byte, error) {
 		return nil, err
 	}
 
+	if header.Version == Version0 {
+		return result.Bytes(), nil
+	}
+
+	if len(header.NoncePrefix)+StreamNonceOverhead != int(header.NonceSize) {
+		return nil, ErrInvalidNonceSize
+	}
+
+	if _, err := result.Write(header.NoncePrefix); err != nil {
+		return nil, err
+	}
+
+	if err := encryptExtensions(result, header.Extensions); err != nil {
+		return nil, err
+	}
+
 	return result.Bytes(), nil
 }
+
+// AssociatedData returns the header bytes authenticated with every chunk.
+// Key slots and their padding are left out: each slot is authenticated by its
+// own wrap, and leaving them out lets slots change without touching the
+// chunks.
+func AssociatedData(header *Header) ([]byte, error) {
+	authenticated := *header
+	authenticated.Extensions = nil
+
+	for _, ext := range header.Extensions {
+		if ext.Type != ExtensionKeySlots && ext.Type != ExtensionPadding {
+			authenticated.Extensions = append(authenticated.Extensions, ext)
+		}
+	}
+
+	return EncryptHeader(&authenticated)
+}
@@ -0,0 +1,263 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newTestHeader(version uint8) *Header {
+	salt := GenerateSalt(DefaultSaltSize)
+	header := NewHeader(3, 4096, len(salt), 12, salt, GenerateNoncePrefix(12))
+	header.Version = version
+
+	switch version {
+	case Version0:
+		header.NoncePrefix = nil
+	default:
+		header.Extensions = []Extension{}
+	}
+
+	return header
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	type Case struct {
+		name   string
+		header *Header
+	}
+
+	withExtensions := newTestHeader(Version2)
+	withExtensions.SetExtension(100, false, []byte("optional"))
+	withExtensions.SetExtension(101, false, []byte{})
+
+	cases := []Case{
+		{
+			name:   "v0",
+			header: newTestHeader(Version0),
+		},
+		{
+			name:   "v2",
+			header: newTestHeader(Version2),
+		},
+		{
+			name:   "v2 with extensions",
+			header: withExtensions,
+		},
+	}
+
+	for _, item := range cases {
+		encHeader, err := EncryptHeader(item.header)
+		if err != nil {
+			t.Fatalf("[%s] %v", item.name, err)
+		}
+
+		// the chunk stream follows the header
+		r := bytes.NewReader(append(encHeader, 0xff))
+
+		header, err := DecryptHeader(r)
+		if err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", item.name, err, nil)
+		}
+		if !reflect.DeepEqual(header, item.header) {
+			t.Fatalf("[%s] get header: %+v, expected header: %+v", item.name, header, item.header)
+		}
+		if r.Len() != 1 {
+			t.Fatalf("[%s] header read %d bytes of the chunk stream", item.name, 1-r.Len())
+		}
+	}
+}
+
+func TestDecryptHeaderExtensions(t *testing.T) {
+	type Case struct {
+		name       string
+		extensions []Extension
+		wantErr    error
+	}
+
+	knownExtensions[7] = true
+	defer delete(knownExtensions, 7)
+
+	cases := []Case{
+		{
+			name:       "unknown optional",
+			extensions: []Extension{{Type: 100, Value: []byte("value")}},
+			wantErr:    nil,
+		},
+		{
+			name:       "known critical",
+			extensions: []Extension{{Type: 7, Critical: true, Value: []byte("value")}},
+			wantErr:    nil,
+		},
+		{
+			name:       "unknown critical",
+			extensions: []Extension{{Type: 100, Value: []byte("value")}, {Type: 101, Critical: true}},
+			wantErr:    ErrUnknownCriticalExtension,
+		},
+	}
+
+	for _, item := range cases {
+		header := newTestHeader(Version2)
+		header.Extensions = item.extensions
+
+		encHeader, err := EncryptHeader(header)
+		if err != nil {
+			t.Fatalf("[%s] %v", item.name, err)
+		}
+
+		decHeader, err := DecryptHeader(bytes.NewReader(encHeader))
+		if !errors.Is(err, item.wantErr) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", item.name, err, item.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+
+		// skipped extensions are kept so the header authenticates
+		reencHeader, err := EncryptHeader(decHeader)
+		if err != nil {
+			t.Fatalf("[%s] %v", item.name, err)
+		}
+		if !bytes.Equal(reencHeader, encHeader) {
+			t.Fatalf("[%s] header changed after decrypting", item.name)
+		}
+	}
+}
+
+func TestDecryptHeaderInvalid(t *testing.T) {
+	type Case struct {
+		name    string
+		data    []byte
+		wantErr error
+	}
+
+	encHeader, err := EncryptHeader(newTestHeader(Version2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []Case{
+		{
+			name:    "invalid magic number",
+			data:    append([]byte("CRPX"), encHeader[4:]...),
+			wantErr: ErrInvalidMagicNum,
+		},
+		{
+			name:    "unsupported version",
+			data:    append([]byte{'C', 'R', 'P', 'T', versionMarker, CurrentVersion + 1}, encHeader[6:]...),
+			wantErr: ErrUnsupportedVersion,
+		},
+		{
+			name:    "unreleased version 1",
+			data:    append([]byte{'C', 'R', 'P', 'T', versionMarker, 1}, encHeader[6:]...),
+			wantErr: ErrUnsupportedVersion,
+		},
+	}
+
+	for _, item := range cases {
+		_, err := DecryptHeader(bytes.NewReader(item.data))
+		if !errors.Is(err, item.wantErr) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", item.name, err, item.wantErr)
+		}
+	}
+}
+
+func TestDecryptHeaderSizes(t *testing.T) {
+	type Case struct {
+		name    string
+		header  func() *Header
+		wantErr error
+	}
+
+	withBlockSize := func(version uint8, blockSize uint64) func() *Header {
+		return func() *Header {
+			header := newTestHeader(version)
+			header.BlockSize = blockSize
+			return header
+		}
+	}
+
+	cases := []Case{
+		{
+			name:    "min block size",
+			header:  withBlockSize(Version2, MinBlockSize),
+			wantErr: nil,
+		},
+		{
+			name:    "max block size",
+			header:  withBlockSize(Version2, MaxBlockSize),
+			wantErr: nil,
+		},
+		{
+			name:    "zero block size",
+			header:  withBlockSize(Version2, 0),
+			wantErr: ErrInvalidBlockSize,
+		},
+		{
+			name:    "block size below min",
+			header:  withBlockSize(Version2, MinBlockSize-1),
+			wantErr: ErrInvalidBlockSize,
+		},
+		{
+			name:    "block size above max",
+			header:  withBlockSize(Version2, MaxBlockSize+1),
+			wantErr: ErrInvalidBlockSize,
+		},
+		{
+			name:    "huge block size",
+			header:  withBlockSize(Version2, 1<<40),
+			wantErr: ErrInvalidBlockSize,
+		},
+		{
+			name:    "v0 whole file chunk",
+			header:  withBlockSize(Version0, MaxLegacyBlockSize),
+			wantErr: nil,
+		},
+		{
+			name:    "v0 empty file",
+			header:  withBlockSize(Version0, 0),
+			wantErr: nil,
+		},
+		{
+			name:    "v0 huge block size",
+			header:  withBlockSize(Version0, MaxLegacyBlockSize+1),
+			wantErr: ErrInvalidBlockSize,
+		},
+		{
+			name: "huge salt",
+			header: func() *Header {
+				header := newTestHeader(Version2)
+				header.SaltSize = 1 << 31
+				return header
+			},
+			wantErr: ErrInvalidSaltSize,
+		},
+	}
+
+	for _, item := range cases {
+		encHeader, err := EncryptHeader(item.header())
+		if err != nil {
+			t.Fatalf("[%s] %v", item.name, err)
+		}
+
+		_, err = DecryptHeader(bytes.NewReader(encHeader))
+		if !errors.Is(err, item.wantErr) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", item.name, err, item.wantErr)
+		}
+	}
+
+	// the nonce size is followed by a prefix of that size
+	header := newTestHeader(Version2)
+	encHeader, err := EncryptHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(encHeader[20+header.SaltSize:], 1<<31)
+
+	_, err = DecryptHeader(bytes.NewReader(encHeader))
+	if !errors.Is(err, ErrInvalidNonceSize) {
+		t.Fatalf("[huge nonce] get error: %v, expected error: %v", err, ErrInvalidNonceSize)
+	}
+}
@@ -0,0 +1,196 @@
+package crypto
+
+import (
+	"fmt"
+	"math"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+type KDF uint8
+
+const (
+	KDFPBKDF2   KDF = 1
+	KDFScrypt   KDF = 2
+	KDFArgon2id KDF = 3
+
+	KDFNamePBKDF2   = "pbkdf2"
+	KDFNameScrypt   = "scrypt"
+	KDFNameArgon2id = "argon2id"
+
+	// scrypt block size is fixed, so N is the memory cost in KiB
+	scryptR = 8
+
+	maxKDFMemory = 4 * 1024 * 1024
+)
+
+var kdfNames = map[KDF]string{
+	KDFPBKDF2:   KDFNamePBKDF2,
+	KDFScrypt:   KDFNameScrypt,
+	KDFArgon2id: KDFNameArgon2id,
+}
+
+func (k KDF) String() string {
+	if name, ok := kdfNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("kdf(%d)", uint8(k))
+}
+
+func ParseKDF(name string) (KDF, error) {
+	for kdf, kdfName := range kdfNames {
+		if kdfName == name {
+			return kdf, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownKDF, name)
+}
+
+// KDFParams are the cost parameters of a password based key derivation.
+// Fields not used by a KDF are zero.
+type KDFParams struct {
+	KDF     KDF
+	Time    uint32 // PBKDF2 iterations, Argon2id passes
+	Memory  uint32 // Argon2id memory and scrypt N, in KiB
+	Threads uint8  // Argon2id lanes, scrypt parallelization
+}
+
+// LegacyKDFParams returns the key derivation of v0 files, which did not
+// record it.
+func LegacyKDFParams() *KDFParams {
+	return &KDFParams{KDF: KDFPBKDF2, Time: 100000}
+}
+
+func DefaultKDFParams(kdf KDF) *KDFParams {
+	switch kdf {
+	case KDFScrypt:
+		return &KDFParams{KDF: KDFScrypt, Memory: 32 * 1024, Threads: 1}
+	case KDFArgon2id:
+		return &KDFParams{KDF: KDFArgon2id, Time: 3, Memory: 64 * 1024, Threads: 4}
+	default:
+		return &KDFParams{KDF: KDFPBKDF2, Time: 100000}
+	}
+}
+
+func (p *KDFParams) Validate() error {
+	switch p.KDF {
+	case KDFPBKDF2:
+		if p.Time == 0 {
+			return fmt.Errorf("%w: pbkdf2 iterations must be positive", ErrInvalidKDFParams)
+		}
+	case KDFScrypt:
+		if p.Memory < 2 || p.Memory&(p.Memory-1) != 0 {
+			return fmt.Errorf("%w: scrypt memory must be a power of two", ErrInvalidKDFParams)
+		}
+		if p.Memory > maxKDFMemory {
+			return fmt.Errorf("%w: scrypt memory is too large", ErrInvalidKDFParams)
+		}
+		if p.Threads == 0 {
+			return fmt.Errorf("%w: scrypt threads must be positive", ErrInvalidKDFParams)
+		}
+	case KDFArgon2id:
+		if p.Time == 0 || p.Threads == 0 {
+			return fmt.Errorf("%w: argon2id time and threads must be positive", ErrInvalidKDFParams)
+		}
+		if p.Memory < 8*uint32(p.Threads) {
+			return fmt.Errorf("%w: argon2id memory must be at least 8 KiB per thread", ErrInvalidKDFParams)
+		}
+		if p.Memory > maxKDFMemory {
+			return fmt.Errorf("%w: argon2id memory is too large", ErrInvalidKDFParams)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownKDF, p.KDF)
+	}
+
+	return nil
+}
+
+// KDFLimits caps the cost of key derivations read from a file. The
+// parameters come from the key slots, which are only authenticated by the
+// derived key, so a modified file could otherwise make a decryption run for
+// hours or take gigabytes of memory.
+type KDFLimits struct {
+	// Iterations is the most PBKDF2 iterations
+	Iterations uint32
+	// Memory is the most scrypt and argon2id memory, in KiB
+	Memory uint32
+	// Work is the most memory times passes over it: argon2id memory times
+	// time, scrypt N times parallelization
+	Work uint64
+}
+
+// DefaultKDFLimits are several times the costs kdf calibrate picks for a
+// one second target on a fast machine.
+var DefaultKDFLimits = KDFLimits{
+	Iterations: 20_000_000,
+	Memory:     1 << 20,
+	Work:       16 << 20,
+}
+
+// Scale multiplies every limit by factor, a factor of zero keeps them.
+func (l KDFLimits) Scale(factor uint32) KDFLimits {
+	if factor == 0 {
+		return l
+	}
+
+	return KDFLimits{
+		Iterations: uint32(min(uint64(l.Iterations)*uint64(factor), math.MaxUint32)),
+		Memory:     uint32(min(uint64(l.Memory)*uint64(factor), maxKDFMemory)),
+		Work:       l.Work * uint64(factor),
+	}
+}
+
+// CheckLimits fails with ErrKDFLimit if deriving a key with p costs more
+// than limits allow.
+func (p *KDFParams) CheckLimits(limits KDFLimits) error {
+	switch p.KDF {
+	case KDFPBKDF2:
+		if p.Time > limits.Iterations {
+			return fmt.Errorf("%w: %s, at most %d iterations", ErrKDFLimit, p, limits.Iterations)
+		}
+		return nil
+	case KDFScrypt:
+		return checkMemoryLimits(p, uint64(p.Threads), limits)
+	default:
+		return checkMemoryLimits(p, uint64(p.Time), limits)
+	}
+}
+
+func checkMemoryLimits(p *KDFParams, passes uint64, limits KDFLimits) error {
+	if p.Memory > limits.Memory {
+		return fmt.Errorf("%w: %s, at most %d KiB of memory", ErrKDFLimit, p, limits.Memory)
+	}
+	if uint64(p.Memory)*passes > limits.Work {
+		return fmt.Errorf("%w: %s, at most %d KiB of memory passes", ErrKDFLimit, p, limits.Work)
+	}
+	return nil
+}
+
+func (p *KDFParams) String() string {
+	switch p.KDF {
+	case KDFPBKDF2:
+		return fmt.Sprintf("%s (iterations=%d)", p.KDF, p.Time)
+	case KDFScrypt:
+		return fmt.Sprintf("%s (N=%d, r=%d, p=%d)", p.KDF, p.Memory, scryptR, p.Threads)
+	default:
+		return fmt.Sprintf("%s (time=%d, memory=%d KiB, threads=%d)", p.KDF, p.Time, p.Memory, p.Threads)
+	}
+}
+
+func DeriveKey(password, salt []byte, keySize int, params *KDFParams) ([]byte, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch params.KDF {
+	case KDFScrypt:
+		return scrypt.Key(password, salt, int(params.Memory), scryptR, int(params.Threads), keySize)
+	case KDFArgon2id:
+		return argon2.IDKey(password, salt, params.Time, params.Memory, params.Threads, uint32(keySize)), nil
+	default:
+		return pbkdf2.Key(password, salt, int(params.Time), keySize, sha3.New256), nil
+	}
+}
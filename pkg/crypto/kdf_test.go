@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDeriveKey(t *testing.T) {
+	cases := []*KDFParams{
+		{KDF: KDFPBKDF2, Time: 1000},
+		{KDF: KDFScrypt, Memory: 1024, Threads: 1},
+		{KDF: KDFArgon2id, Time: 1, Memory: 1024, Threads: 2},
+	}
+
+	salt := GenerateSalt(DefaultSaltSize)
+	keys := [][]byte{}
+
+	for _, params := range cases {
+		key, err := DeriveKey([]byte("password"), salt, 32, params)
+		if err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", params, err, nil)
+		}
+
+		again, err := DeriveKey([]byte("password"), salt, 32, params)
+		if err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", params, err, nil)
+		}
+		if !bytes.Equal(key, again) {
+			t.Fatalf("[%s] key derivation is not deterministic", params)
+		}
+
+		for _, other := range keys {
+			if bytes.Equal(key, other) {
+				t.Fatalf("[%s] different kdf derived the same key", params)
+			}
+		}
+		keys = append(keys, key)
+	}
+}
+
+func TestKDFParamsCheckLimits(t *testing.T) {
+	type Case struct {
+		params  *KDFParams
+		limits  KDFLimits
+		wantErr error
+	}
+
+	cases := []Case{
+		{params: DefaultKDFParams(KDFPBKDF2), limits: DefaultKDFLimits, wantErr: nil},
+		{params: DefaultKDFParams(KDFScrypt), limits: DefaultKDFLimits, wantErr: nil},
+		{params: DefaultKDFParams(KDFArgon2id), limits: DefaultKDFLimits, wantErr: nil},
+		{params: &KDFParams{KDF: KDFPBKDF2, Time: 1 << 31}, limits: DefaultKDFLimits, wantErr: ErrKDFLimit},
+		{params: &KDFParams{KDF: KDFScrypt, Memory: maxKDFMemory, Threads: 1}, limits: DefaultKDFLimits, wantErr: ErrKDFLimit},
+		{params: &KDFParams{KDF: KDFScrypt, Memory: 1024, Threads: 255}, limits: DefaultKDFLimits, wantErr: nil},
+		{params: &KDFParams{KDF: KDFArgon2id, Time: 1, Memory: maxKDFMemory, Threads: 4}, limits: DefaultKDFLimits, wantErr: ErrKDFLimit},
+		{params: &KDFParams{KDF: KDFArgon2id, Time: 1 << 20, Memory: 64 * 1024, Threads: 4}, limits: DefaultKDFLimits, wantErr: ErrKDFLimit},
+		{params: &KDFParams{KDF: KDFArgon2id, Time: 1, Memory: maxKDFMemory, Threads: 4}, limits: DefaultKDFLimits.Scale(4), wantErr: nil},
+		{params: &KDFParams{KDF: KDFPBKDF2, Time: 1 << 31}, limits: DefaultKDFLimits.Scale(1000), wantErr: nil},
+	}
+
+	for _, item := range cases {
+		err := item.params.CheckLimits(item.limits)
+		if !errors.Is(err, item.wantErr) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", item.params, err, item.wantErr)
+		}
+	}
+}
+
+func TestKDFParamsValidate(t *testing.T) {
+	type Case struct {
+		params  *KDFParams
+		wantErr error
+	}
+
+	cases := []Case{
+		{params: DefaultKDFParams(KDFPBKDF2), wantErr: nil},
+		{params: DefaultKDFParams(KDFScrypt), wantErr: nil},
+		{params: DefaultKDFParams(KDFArgon2id), wantErr: nil},
+		{params: &KDFParams{KDF: KDFPBKDF2}, wantErr: ErrInvalidKDFParams},
+		{params: &KDFParams{KDF: KDFScrypt, Memory: 1000, Threads: 1}, wantErr: ErrInvalidKDFParams},
+		{params: &KDFParams{KDF: KDFArgon2id, Time: 1, Memory: 8, Threads: 4}, wantErr: ErrInvalidKDFParams},
+		{params: &KDFParams{KDF: KDFArgon2id, Time: 1, Memory: maxKDFMemory + 1, Threads: 4}, wantErr: ErrInvalidKDFParams},
+		{params: &KDFParams{KDF: 42}, wantErr: ErrUnknownKDF},
+	}
+
+	for _, item := range cases {
+		if err := item.params.Validate(); !errors.Is(err, item.wantErr) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", item.params, err, item.wantErr)
+		}
+	}
+}
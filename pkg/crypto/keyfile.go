@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+type KeyMode uint8
+
+const (
+	// KeyModePassword derives the key from a password with LegacyKDFParams.
+	// Only v0 files use it, passwords of later versions wrap a random key
+	// in a key slot.
+	KeyModePassword KeyMode = 0
+	// KeyModeRawKey uses a random key kept in a key file.
+	KeyModeRawKey KeyMode = 1
+	// KeyModeSlots uses a random key wrapped in the header key slots.
+	KeyModeSlots KeyMode = 2
+)
+
+const (
+	KeyFormatRaw    = "raw"
+	KeyFormatHex    = "hex"
+	KeyFormatBase64 = "base64"
+)
+
+func (m KeyMode) String() string {
+	switch m {
+	case KeyModePassword:
+		return "password"
+	case KeyModeRawKey:
+		return "raw key"
+	case KeyModeSlots:
+		return "key slots"
+	}
+	return fmt.Sprintf("key mode(%d)", uint8(m))
+}
+
+// KeyMode returns how the file key is obtained. v0 headers have no
+// extensions and always use a password.
+func (h *Header) KeyMode() (KeyMode, error) {
+	if h.Version == Version0 {
+		return KeyModePassword, nil
+	}
+
+	value, ok := h.Extension(ExtensionKeyMode)
+	if !ok {
+		return 0, fmt.Errorf("%w: key mode is missing", ErrInvalidExtension)
+	}
+
+	if len(value) != 1 {
+		return 0, fmt.Errorf("%w: key mode", ErrInvalidExtension)
+	}
+
+	mode := KeyMode(value[0])
+	switch mode {
+	case KeyModeRawKey, KeyModeSlots:
+		return mode, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownKeyMode, mode)
+}
+
+func (h *Header) SetKeyMode(mode KeyMode) {
+	h.SetExtension(ExtensionKeyMode, true, []byte{byte(mode)})
+}
+
+func EncodeKey(key []byte, format string) ([]byte, error) {
+	switch format {
+	case KeyFormatRaw:
+		return key, nil
+	case KeyFormatHex:
+		return []byte(hex.EncodeToString(key) + "\n"), nil
+	case KeyFormatBase64:
+		return []byte(base64.StdEncoding.EncodeToString(key) + "\n"), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKeyFormat, format)
+}
+
+// DecodeKey reads a key file written in any of the key formats.
+func DecodeKey(data []byte, keySize int) ([]byte, error) {
+	if len(data) == keySize {
+		return data, nil
+	}
+
+	text := bytes.TrimSpace(data)
+
+	if len(text) == hex.EncodedLen(keySize) {
+		key, err := hex.DecodeString(string(text))
+		if err == nil {
+			return key, nil
+		}
+	}
+
+	if len(text) == base64.StdEncoding.EncodedLen(keySize) {
+		key, err := base64.StdEncoding.DecodeString(string(text))
+		if err == nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: expected a %d byte key in raw, hex or base64 format", ErrInvalidKeyFile, keySize)
+}
@@ -0,0 +1,65 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDecodeKey(t *testing.T) {
+	formats := []string{KeyFormatRaw, KeyFormatHex, KeyFormatBase64}
+
+	for _, keySize := range []int{16, 24, 32} {
+		key := GenerateKey(keySize)
+
+		for _, format := range formats {
+			data, err := EncodeKey(key, format)
+			if err != nil {
+				t.Fatalf("[%s] %v", format, err)
+			}
+
+			decoded, err := DecodeKey(data, keySize)
+			if err != nil {
+				t.Fatalf("[%s] get error: %v, expected error: %v", format, err, nil)
+			}
+			if !bytes.Equal(decoded, key) {
+				t.Fatalf("[%s] decoded key does not match", format)
+			}
+
+			// a key file for another algorithm
+			if _, err := DecodeKey(data, 2*keySize); !errors.Is(err, ErrInvalidKeyFile) {
+				t.Fatalf("[%s] get error: %v, expected error: %v", format, err, ErrInvalidKeyFile)
+			}
+		}
+	}
+
+	if _, err := EncodeKey(GenerateKey(32), "pem"); !errors.Is(err, ErrUnknownKeyFormat) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrUnknownKeyFormat)
+	}
+}
+
+func TestHeaderKeyMode(t *testing.T) {
+	mode, err := newTestHeader(Version0).KeyMode()
+	if err != nil || mode != KeyModePassword {
+		t.Fatalf("get key mode: %s (%v), expected: %s", mode, err, KeyModePassword)
+	}
+
+	header := newTestHeader(Version2)
+	if _, err := header.KeyMode(); !errors.Is(err, ErrInvalidExtension) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrInvalidExtension)
+	}
+
+	header.SetKeyMode(KeyModeRawKey)
+	mode, err = header.KeyMode()
+	if err != nil || mode != KeyModeRawKey {
+		t.Fatalf("get key mode: %s (%v), expected: %s", mode, err, KeyModeRawKey)
+	}
+
+	// passwords of versioned files are always in key slots
+	for _, mode := range []KeyMode{KeyModePassword, 42} {
+		header.SetKeyMode(mode)
+		if _, err := header.KeyMode(); !errors.Is(err, ErrUnknownKeyMode) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", mode, err, ErrUnknownKeyMode)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// kdfParamsSize is the size of binary encoded KDFParams.
+var kdfParamsSize = binary.Size(KDFParams{})
+
+// WrapWithPassword seals the file key with a key derived from the password.
+// The slot body is the KDF parameters, a fresh salt and the sealed file key.
+func WrapWithPassword(password, fileKey []byte, params *KDFParams) (*KeySlot, error) {
+	salt := GenerateSalt(DefaultSaltSize)
+
+	wrapKey, err := DeriveKey(password, salt, chacha20poly1305.KeySize, params)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+
+	body := bytes.NewBuffer([]byte{})
+	if err := binary.Write(body, binary.LittleEndian, params); err != nil {
+		return nil, err
+	}
+	body.Write(salt)
+
+	// every slot has its own salt and so its own wrap key, a zero nonce is safe
+	nonce := make([]byte, aead.NonceSize())
+	body.Write(aead.Seal(nil, nonce, fileKey, nil))
+
+	return &KeySlot{Type: SlotPassword, Body: body.Bytes()}, nil
+}
+
+// UnwrapWithPassword opens a password slot. It fails with ErrSlotMismatch if
+// the password is wrong and with ErrKDFLimit if the slot costs more than
+// limits allow.
+func UnwrapWithPassword(slot KeySlot, password []byte, limits KDFLimits) ([]byte, error) {
+	if slot.Type != SlotPassword {
+		return nil, ErrSlotMismatch
+	}
+
+	params, err := slot.KDFParams()
+	if err != nil {
+		return nil, err
+	}
+	if err := params.CheckLimits(limits); err != nil {
+		return nil, err
+	}
+
+	body := slot.Body[kdfParamsSize:]
+	salt, sealed := body[:DefaultSaltSize], body[DefaultSaltSize:]
+
+	wrapKey, err := DeriveKey(password, salt, chacha20poly1305.KeySize, params)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	fileKey, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrSlotMismatch
+	}
+
+	return fileKey, nil
+}
+
+// KDFParams returns the key derivation parameters of a password slot.
+func (s KeySlot) KDFParams() (*KDFParams, error) {
+	if s.Type != SlotPassword || len(s.Body) < kdfParamsSize+DefaultSaltSize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("%w: password slot", ErrInvalidExtension)
+	}
+
+	params := &KDFParams{}
+	if err := binary.Read(bytes.NewReader(s.Body[:kdfParamsSize]), binary.LittleEndian, params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKDFParams, err)
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
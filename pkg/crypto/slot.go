@@ -0,0 +1,209 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+type SlotType uint8
+
+const (
+	// SlotX25519 wraps the file key for an X25519 recipient.
+	SlotX25519 SlotType = 1
+	// SlotPassword wraps the file key with a key derived from a password.
+	SlotPassword SlotType = 2
+)
+
+func (t SlotType) String() string {
+	switch t {
+	case SlotX25519:
+		return "x25519"
+	case SlotPassword:
+		return "password"
+	}
+	return fmt.Sprintf("slot(%d)", uint8(t))
+}
+
+// KeySlot holds the file key wrapped for one way of unlocking the file.
+type KeySlot struct {
+	Type SlotType
+	Body []byte
+}
+
+// KeySlots returns the key slots stored in the header.
+func (h *Header) KeySlots() ([]KeySlot, error) {
+	value, ok := h.Extension(ExtensionKeySlots)
+	if !ok {
+		return nil, nil
+	}
+
+	r := bytes.NewReader(value)
+
+	var count uint16
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("%w: key slots", ErrInvalidExtension)
+	}
+
+	slots := make([]KeySlot, 0, count)
+	for range count {
+		var (
+			t      SlotType
+			length uint16
+		)
+		if err := binary.Read(r, binary.LittleEndian, &t); err != nil {
+			return nil, fmt.Errorf("%w: key slots", ErrInvalidExtension)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, fmt.Errorf("%w: key slots", ErrInvalidExtension)
+		}
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, fmt.Errorf("%w: key slots", ErrInvalidExtension)
+		}
+
+		slots = append(slots, KeySlot{Type: t, Body: body})
+	}
+
+	return slots, nil
+}
+
+// KeySlotsAreaSize is the space a header reserves for the key slots and
+// their padding. Slots are added and removed within it, so the header keeps
+// its size.
+const KeySlotsAreaSize = 4096
+
+// SetKeySlots stores the slots in the header. The slots are padded to fill
+// the space the previous slots and padding took, the header only grows if
+// they do not fit, by a multiple of KeySlotsAreaSize.
+func (h *Header) SetKeySlots(slots []KeySlot) error {
+	if len(slots) > math.MaxUint16 {
+		return fmt.Errorf("%w: too many key slots", ErrInvalidExtension)
+	}
+
+	value := bytes.NewBuffer([]byte{})
+	if err := binary.Write(value, binary.LittleEndian, uint16(len(slots))); err != nil {
+		return err
+	}
+
+	for _, slot := range slots {
+		if len(slot.Body) > math.MaxUint16 {
+			return fmt.Errorf("%w: key slot is too large", ErrInvalidExtension)
+		}
+
+		if err := binary.Write(value, binary.LittleEndian, slot.Type); err != nil {
+			return err
+		}
+		if err := binary.Write(value, binary.LittleEndian, uint16(len(slot.Body))); err != nil {
+			return err
+		}
+		if _, err := value.Write(slot.Body); err != nil {
+			return err
+		}
+	}
+
+	area := h.keySlotsArea()
+	h.SetExtension(ExtensionKeySlots, true, value.Bytes())
+	h.padKeySlots(area)
+	return nil
+}
+
+// keySlotsArea returns the size of the key slots and padding extensions.
+func (h *Header) keySlotsArea() int {
+	size := 0
+	for _, ext := range h.Extensions {
+		if ext.Type == ExtensionKeySlots || ext.Type == ExtensionPadding {
+			size += extensionRecordSize + len(ext.Value)
+		}
+	}
+	return size
+}
+
+// padKeySlots pads the key slots to take area bytes with the padding, or
+// the next multiple of KeySlotsAreaSize if they do not fit.
+func (h *Header) padKeySlots(area int) {
+	slots, _ := h.Extension(ExtensionKeySlots)
+	used := extensionRecordSize + len(slots)
+
+	rest := area - used
+	switch {
+	case rest == 0:
+		h.removeExtension(ExtensionPadding)
+		return
+	case rest < extensionRecordSize:
+		area = (used + extensionRecordSize + KeySlotsAreaSize - 1) / KeySlotsAreaSize * KeySlotsAreaSize
+		rest = area - used
+	}
+
+	h.SetExtension(ExtensionPadding, false, make([]byte, rest-extensionRecordSize))
+}
+
+// WrapKey wraps the file key for every password and every recipient.
+func WrapKey(fileKey []byte, passwords [][]byte, recipients []*Recipient, params *KDFParams) ([]KeySlot, error) {
+	slots := make([]KeySlot, 0, len(passwords)+len(recipients))
+	for _, password := range passwords {
+		slot, err := WrapWithPassword(password, fileKey, params)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, *slot)
+	}
+
+	for _, recipient := range recipients {
+		slot, err := recipient.Wrap(fileKey)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, *slot)
+	}
+
+	return slots, nil
+}
+
+// UnwrapKey returns the file key from the first slot opened by one of the
+// passwords or identities, and the index of that slot. Password slots are
+// only opened within limits. If no slot opens, the error of the first slot
+// that failed other than by a mismatch is returned, ErrNoMatchingSlot if
+// there is none.
+func UnwrapKey(slots []KeySlot, keySize int, passwords [][]byte, identities []*Identity, limits KDFLimits) ([]byte, int, error) {
+	unwraps := []func(KeySlot) ([]byte, error){}
+	for _, password := range passwords {
+		unwraps = append(unwraps, func(slot KeySlot) ([]byte, error) {
+			return UnwrapWithPassword(slot, password, limits)
+		})
+	}
+	for _, identity := range identities {
+		unwraps = append(unwraps, identity.Unwrap)
+	}
+
+	// a slot that cannot be opened does not stop the others from being tried
+	var slotErr error
+	for i, slot := range slots {
+		for _, unwrap := range unwraps {
+			key, err := unwrap(slot)
+			if err == nil && len(key) != keySize {
+				err = fmt.Errorf("%w: unexpected key size", ErrInvalidExtension)
+			}
+			if errors.Is(err, ErrSlotMismatch) {
+				continue
+			}
+			if err != nil {
+				if slotErr == nil {
+					slotErr = fmt.Errorf("key slot %d: %w", i, err)
+				}
+				continue
+			}
+
+			return key, i, nil
+		}
+	}
+
+	if slotErr != nil {
+		return nil, 0, slotErr
+	}
+	return nil, 0, ErrNoMatchingSlot
+}
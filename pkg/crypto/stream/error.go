@@ -0,0 +1,33 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTooManyChunks       = errors.New("too many chunks in the stream")
+	ErrTruncated           = errors.New("stream is truncated: final chunk is missing")
+	ErrTrailingData        = errors.New("unexpected data after the final chunk")
+	ErrChunkAuthentication = errors.New("chunk authentication failed: data is corrupted or chunks are reordered")
+)
+
+type ChunkError struct {
+	Index uint64
+	Err   error
+}
+
+func (ce *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d: %v", ce.Index, ce.Err)
+}
+
+func (ce *ChunkError) Unwrap() error {
+	return ce.Err
+}
+
+func chunkError(index uint64, err error) error {
+	return &ChunkError{
+		Index: index,
+		Err:   err,
+	}
+}
@@ -0,0 +1,228 @@
+package stream
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+)
+
+const (
+	flagChunk  = 0
+	flagLast   = 1
+	flagHeader = 2
+
+	MaxChunks = math.MaxUint32 + 1
+)
+
+// nonces reuses nonce buffers, Seal and Open may be called from several
+// goroutines at once.
+type nonces struct {
+	prefix []byte
+	pool   sync.Pool
+}
+
+func newNonces(prefix []byte) *nonces {
+	size := len(prefix) + crypto.StreamNonceOverhead
+	return &nonces{
+		prefix: prefix,
+		pool: sync.Pool{New: func() any {
+			n := make([]byte, size)
+			return &n
+		}},
+	}
+}
+
+// get builds a STREAM nonce: prefix || index (big endian uint32) || flag.
+// It is returned to the pool with put.
+func (ns *nonces) get(index uint64, flag byte) *[]byte {
+	p := ns.pool.Get().(*[]byte)
+	n := *p
+	copy(n, ns.prefix)
+	binary.BigEndian.PutUint32(n[len(ns.prefix):], uint32(index))
+	n[len(n)-1] = flag
+
+	return p
+}
+
+func (ns *nonces) put(n *[]byte) {
+	ns.pool.Put(n)
+}
+
+func chunkFlag(last bool) byte {
+	if last {
+		return flagLast
+	}
+	return flagChunk
+}
+
+type Encryptor struct {
+	alg            algorithms.CipherAlgorithm
+	nonces         *nonces
+	additionalData []byte
+}
+
+func NewEncryptor(alg algorithms.CipherAlgorithm, header *crypto.Header) (*Encryptor, error) {
+	if header.Version != crypto.CurrentVersion {
+		return nil, crypto.ErrUnsupportedVersion
+	}
+	if len(header.NoncePrefix)+crypto.StreamNonceOverhead != alg.GetNonceSize() {
+		return nil, crypto.ErrInvalidNonceSize
+	}
+
+	additionalData, err := crypto.AssociatedData(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Encryptor{
+		alg:            alg,
+		nonces:         newNonces(header.NoncePrefix),
+		additionalData: additionalData,
+	}, nil
+}
+
+// SealHeader returns the tag written right after the header, so a modified
+// header is reported before any chunk is opened.
+func (e *Encryptor) SealHeader() ([]byte, error) {
+	n := e.nonces.get(0, flagHeader)
+	defer e.nonces.put(n)
+
+	return e.alg.Encrypt(nil, nil, *n, e.additionalData)
+}
+
+// Seal encrypts the chunk at the given position of the stream and appends
+// the result to dst.
+func (e *Encryptor) Seal(dst []byte, index uint64, plaintext []byte, last bool) ([]byte, error) {
+	if index >= MaxChunks {
+		return nil, ErrTooManyChunks
+	}
+
+	n := e.nonces.get(index, chunkFlag(last))
+	defer e.nonces.put(n)
+
+	return e.alg.Encrypt(dst, plaintext, *n, e.additionalData)
+}
+
+type Decryptor struct {
+	alg            algorithms.CipherAlgorithm
+	version        uint8
+	nonces         *nonces
+	additionalData []byte
+}
+
+func NewDecryptor(alg algorithms.CipherAlgorithm, header *crypto.Header) (*Decryptor, error) {
+	if header.Version != crypto.Version0 &&
+		len(header.NoncePrefix)+crypto.StreamNonceOverhead != alg.GetNonceSize() {
+		return nil, crypto.ErrInvalidNonceSize
+	}
+
+	// v0 chunks are sealed without associated data
+	var additionalData []byte
+	if header.Version != crypto.Version0 {
+		ad, err := crypto.AssociatedData(header)
+		if err != nil {
+			return nil, err
+		}
+		additionalData = ad
+	}
+
+	return &Decryptor{
+		alg:            alg,
+		version:        header.Version,
+		nonces:         newNonces(header.NoncePrefix),
+		additionalData: additionalData,
+	}, nil
+}
+
+// HeaderTagSize returns the size of the tag following the header. v0 files
+// have no header tag.
+func (d *Decryptor) HeaderTagSize() int {
+	if d.version == crypto.Version0 {
+		return 0
+	}
+	return d.alg.GetTagSize()
+}
+
+// ChunkNonceSize returns the size of the nonce stored in front of every chunk.
+func (d *Decryptor) ChunkNonceSize() int {
+	if d.version == crypto.Version0 {
+		return d.alg.GetNonceSize()
+	}
+	return 0
+}
+
+// OpenHeader checks the header tag. A v0 header is not authenticated, a wrong
+// key only shows when the first chunk is opened.
+func (d *Decryptor) OpenHeader(tag []byte) error {
+	if len(tag) != d.HeaderTagSize() {
+		return crypto.ErrHeaderAuthentication
+	}
+	if d.version == crypto.Version0 {
+		return nil
+	}
+
+	n := d.nonces.get(0, flagHeader)
+	defer d.nonces.put(n)
+
+	if _, err := d.alg.Decrypt(nil, tag, *n, d.additionalData); err != nil {
+		return crypto.ErrHeaderAuthentication
+	}
+
+	return nil
+}
+
+// Open decrypts the chunk at the given position of the stream and appends
+// the plaintext to dst. nonce is only used by v0 files, where it is stored in
+// front of the chunk.
+func (d *Decryptor) Open(dst []byte, index uint64, nonce, ciphertext []byte, last bool) ([]byte, error) {
+	if d.version == crypto.Version0 {
+		return d.openV0(dst, index, nonce, ciphertext, last)
+	}
+
+	if index >= MaxChunks {
+		return nil, chunkError(index, ErrTooManyChunks)
+	}
+	if len(ciphertext) < d.alg.GetTagSize() {
+		return nil, chunkError(index, ErrTruncated)
+	}
+
+	plaintext, err := d.open(dst, index, ciphertext, last)
+	if err == nil {
+		return plaintext, nil
+	}
+
+	// The chunk opens with the opposite flag: it is authentic, but the
+	// stream ends in the wrong place.
+	if _, err := d.open(dst, index, ciphertext, !last); err == nil {
+		if last {
+			return nil, chunkError(index, ErrTruncated)
+		}
+		return nil, chunkError(index, ErrTrailingData)
+	}
+
+	return nil, chunkError(index, ErrChunkAuthentication)
+}
+
+func (d *Decryptor) open(dst []byte, index uint64, ciphertext []byte, last bool) ([]byte, error) {
+	n := d.nonces.get(index, chunkFlag(last))
+	defer d.nonces.put(n)
+
+	return d.alg.Decrypt(dst, ciphertext, *n, d.additionalData)
+}
+
+func (d *Decryptor) openV0(dst []byte, index uint64, nonce, ciphertext []byte, last bool) ([]byte, error) {
+	// v0 streams have no final chunk, an empty file has no chunks at all
+	if last && len(nonce) == 0 && len(ciphertext) == 0 {
+		return dst, nil
+	}
+
+	plaintext, err := d.alg.Decrypt(dst, ciphertext, nonce, d.additionalData)
+	if err != nil {
+		return nil, chunkError(index, ErrChunkAuthentication)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,310 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+)
+
+const testBlockSize = 16
+
+func newTestStream(t *testing.T, algName string) (algorithms.CipherAlgorithm, *crypto.Header) {
+	t.Helper()
+
+	id, err := algorithms.IDByName(algName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySize, err := algorithms.KeySize(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alg, err := algorithms.CreateAlgorithmByID(id, crypto.GenerateKey(keySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noncePrefix := crypto.GenerateNoncePrefix(alg.GetNonceSize())
+	header := crypto.NewHeader(id, testBlockSize, 0, alg.GetNonceSize(), nil, noncePrefix)
+
+	return alg, header
+}
+
+func sealChunks(t *testing.T, alg algorithms.CipherAlgorithm, header *crypto.Header, plaintext []byte) [][]byte {
+	t.Helper()
+
+	enc, err := NewEncryptor(alg, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := [][]byte{}
+	for index := uint64(0); ; index++ {
+		n := min(testBlockSize, len(plaintext))
+		// a full block is the last one only if nothing follows it
+		last := len(plaintext) == n
+
+		chunk, err := enc.Seal(nil, index, plaintext[:n], last)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+
+		plaintext = plaintext[n:]
+		if last {
+			return chunks
+		}
+	}
+}
+
+func openChunks(alg algorithms.CipherAlgorithm, header *crypto.Header, chunks [][]byte) ([]byte, error) {
+	dec, err := NewDecryptor(alg, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chunks) == 0 {
+		chunks = [][]byte{{}}
+	}
+
+	result := []byte{}
+	for i, chunk := range chunks {
+		plaintext, err := dec.Open(nil, uint64(i), nil, chunk, i == len(chunks)-1)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, plaintext...)
+	}
+
+	return result, nil
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	algs := []string{algorithms.AlgAES128GCM, algorithms.AlgAES192GCM, algorithms.AlgAES256GCM, algorithms.AlgCHACHA20POLY1305}
+	sizes := []int{0, 1, testBlockSize - 1, testBlockSize, testBlockSize + 1, 3 * testBlockSize, 5*testBlockSize + 7}
+
+	for _, name := range algs {
+		for _, size := range sizes {
+			caseName := fmt.Sprintf("%s: size %d", name, size)
+
+			alg, header := newTestStream(t, name)
+			plaintext := crypto.GenerateKey(size)
+
+			result, err := openChunks(alg, header, sealChunks(t, alg, header, plaintext))
+			if err != nil {
+				t.Fatalf("[%s] get error: %v, expected error: %v", caseName, err, nil)
+			}
+			if !bytes.Equal(result, plaintext) {
+				t.Fatalf("[%s] decrypted data does not match", caseName)
+			}
+		}
+	}
+}
+
+func TestStreamModified(t *testing.T) {
+	type Case struct {
+		name    string
+		modify  func(chunks [][]byte) [][]byte
+		wantErr error
+	}
+
+	cases := []Case{
+		{
+			name: "final chunk dropped",
+			modify: func(chunks [][]byte) [][]byte {
+				return chunks[:len(chunks)-1]
+			},
+			wantErr: ErrTruncated,
+		},
+		{
+			name: "all chunks dropped",
+			modify: func(chunks [][]byte) [][]byte {
+				return nil
+			},
+			wantErr: ErrTruncated,
+		},
+		{
+			name: "chunks swapped",
+			modify: func(chunks [][]byte) [][]byte {
+				chunks[0], chunks[1] = chunks[1], chunks[0]
+				return chunks
+			},
+			wantErr: ErrChunkAuthentication,
+		},
+		{
+			name: "chunk duplicated",
+			modify: func(chunks [][]byte) [][]byte {
+				return append(chunks[:2], chunks[1:]...)
+			},
+			wantErr: ErrChunkAuthentication,
+		},
+		{
+			name: "final chunk duplicated",
+			modify: func(chunks [][]byte) [][]byte {
+				return append(chunks, chunks[len(chunks)-1])
+			},
+			wantErr: ErrTrailingData,
+		},
+		{
+			name: "chunk modified",
+			modify: func(chunks [][]byte) [][]byte {
+				chunks[1][0] ^= 0x01
+				return chunks
+			},
+			wantErr: ErrChunkAuthentication,
+		},
+	}
+
+	for _, item := range cases {
+		alg, header := newTestStream(t, algorithms.AlgAES256GCM)
+		chunks := sealChunks(t, alg, header, crypto.GenerateKey(4*testBlockSize))
+
+		_, err := openChunks(alg, header, item.modify(chunks))
+		if !errors.Is(err, item.wantErr) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", item.name, err, item.wantErr)
+		}
+	}
+}
+
+func TestOpenHeader(t *testing.T) {
+	algs := []string{algorithms.AlgAES128GCM, algorithms.AlgAES192GCM, algorithms.AlgAES256GCM, algorithms.AlgCHACHA20POLY1305}
+
+	for _, name := range algs {
+		alg, header := newTestStream(t, name)
+		header.BlockSize = crypto.MinBlockSize
+		header.Extensions = []crypto.Extension{}
+		header.SetContentType(crypto.ContentArchive)
+
+		enc, err := NewEncryptor(alg, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tag, err := enc.SealHeader()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dec, err := NewDecryptor(alg, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dec.OpenHeader(tag); err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", name, err, nil)
+		}
+
+		encHeader, err := crypto.EncryptHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// every byte of the header is authenticated, unless it no longer parses
+		for i := range encHeader {
+			caseName := fmt.Sprintf("%s: byte %d", name, i)
+
+			tampered := bytes.Clone(encHeader)
+			tampered[i] ^= 0x01
+
+			tamperedHeader, err := crypto.DecryptHeader(bytes.NewReader(tampered))
+			if err != nil {
+				continue
+			}
+			dec, err := NewDecryptor(alg, tamperedHeader)
+			if err != nil {
+				continue
+			}
+
+			if err := dec.OpenHeader(tag); !errors.Is(err, crypto.ErrHeaderAuthentication) {
+				t.Fatalf("[%s] get error: %v, expected error: %v", caseName, err, crypto.ErrHeaderAuthentication)
+			}
+		}
+	}
+}
+
+func TestOpenV0(t *testing.T) {
+	alg, header := newTestStream(t, algorithms.AlgAES256GCM)
+	header.Version = crypto.Version0
+	header.NoncePrefix = nil
+
+	// v0 chunks are sealed without associated data
+	nonce := crypto.GenerateNonce(alg.GetNonceSize())
+	chunk, err := alg.Encrypt(nil, []byte("secret data"), nonce, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec, err := NewDecryptor(alg, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.HeaderTagSize() != 0 {
+		t.Fatalf("get header tag size: %d, expected: %d", dec.HeaderTagSize(), 0)
+	}
+	if err := dec.OpenHeader(nil); err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+	if dec.ChunkNonceSize() != alg.GetNonceSize() {
+		t.Fatalf("get chunk nonce size: %d, expected: %d", dec.ChunkNonceSize(), alg.GetNonceSize())
+	}
+
+	plaintext, err := dec.Open(nil, 0, nonce, chunk, true)
+	if err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+	if string(plaintext) != "secret data" {
+		t.Fatalf("decrypted data does not match")
+	}
+
+	chunk[0] ^= 1
+	if _, err := dec.Open(nil, 0, nonce, chunk, true); !errors.Is(err, ErrChunkAuthentication) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrChunkAuthentication)
+	}
+}
+
+func TestSealOpenAllocs(t *testing.T) {
+	algs := []string{algorithms.AlgAES256GCM, algorithms.AlgCHACHA20POLY1305}
+
+	for _, name := range algs {
+		alg, header := newTestStream(t, name)
+
+		enc, err := NewEncryptor(alg, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec, err := NewDecryptor(alg, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		plaintext := crypto.GenerateKey(testBlockSize)
+		ciphertext := make([]byte, 0, testBlockSize+alg.GetTagSize())
+		opened := make([]byte, 0, testBlockSize)
+
+		sealAllocs := testing.AllocsPerRun(100, func() {
+			ciphertext, err = enc.Seal(ciphertext[:0], 1, plaintext, false)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sealAllocs != 0 {
+			t.Fatalf("[%s] Seal allocates %v times per chunk, expected 0", name, sealAllocs)
+		}
+
+		openAllocs := testing.AllocsPerRun(100, func() {
+			opened, err = dec.Open(opened[:0], 1, nil, ciphertext, false)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if openAllocs != 0 {
+			t.Fatalf("[%s] Open allocates %v times per chunk, expected 0", name, openAllocs)
+		}
+		if !bytes.Equal(opened, plaintext) {
+			t.Fatalf("[%s] decrypted data does not match", name)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	RecipientPrefix = "crpt-pk-"
+	IdentityPrefix  = "CRPT-SECRET-KEY-"
+
+	x25519WrapInfo = "cryptool x25519 key wrap"
+)
+
+var keyEncoding = base64.RawURLEncoding
+
+// Recipient is an X25519 public key a file key can be wrapped for.
+type Recipient struct {
+	key *ecdh.PublicKey
+}
+
+// Identity is an X25519 private key that unwraps file keys of its recipient.
+type Identity struct {
+	key *ecdh.PrivateKey
+}
+
+func GenerateIdentity() (*Identity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{key: key}, nil
+}
+
+func (i *Identity) String() string {
+	return IdentityPrefix + keyEncoding.EncodeToString(i.key.Bytes())
+}
+
+func (i *Identity) Recipient() *Recipient {
+	return &Recipient{key: i.key.PublicKey()}
+}
+
+func (r *Recipient) String() string {
+	return RecipientPrefix + keyEncoding.EncodeToString(r.key.Bytes())
+}
+
+func ParseRecipient(s string) (*Recipient, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(s), RecipientPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %q prefix", ErrInvalidRecipient, RecipientPrefix)
+	}
+
+	raw, err := keyEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecipient, err)
+	}
+
+	key, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecipient, err)
+	}
+
+	return &Recipient{key: key}, nil
+}
+
+func ParseIdentity(s string) (*Identity, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(s), IdentityPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %q prefix", ErrInvalidIdentity, IdentityPrefix)
+	}
+
+	raw, err := keyEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIdentity, err)
+	}
+
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIdentity, err)
+	}
+
+	return &Identity{key: key}, nil
+}
+
+// ParseIdentities reads an identity file: one identity per line, empty lines
+// and lines starting with # are ignored.
+func ParseIdentities(r io.Reader) ([]*Identity, error) {
+	identities := []*Identity{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		identity, err := ParseIdentity(line)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("%w: no identities found", ErrInvalidIdentity)
+	}
+
+	return identities, nil
+}
+
+// Wrap seals the file key with a key agreed between a fresh ephemeral key and
+// the recipient. The slot body is the ephemeral public key followed by the
+// sealed file key.
+func (r *Recipient) Wrap(fileKey []byte) (*KeySlot, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := ephemeral.ECDH(r.key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := x25519WrapAEAD(shared, ephemeral.PublicKey().Bytes(), r.key.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	// the wrap key is used once, so a zero nonce is safe
+	nonce := make([]byte, aead.NonceSize())
+	body := append(ephemeral.PublicKey().Bytes(), aead.Seal(nil, nonce, fileKey, nil)...)
+
+	return &KeySlot{Type: SlotX25519, Body: body}, nil
+}
+
+// Unwrap opens an X25519 slot. It fails with ErrSlotMismatch if the slot was
+// wrapped for another recipient.
+func (i *Identity) Unwrap(slot KeySlot) ([]byte, error) {
+	if slot.Type != SlotX25519 || len(slot.Body) < 32+chacha20poly1305.Overhead {
+		return nil, ErrSlotMismatch
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(slot.Body[:32])
+	if err != nil {
+		return nil, ErrSlotMismatch
+	}
+
+	shared, err := i.key.ECDH(ephemeral)
+	if err != nil {
+		return nil, ErrSlotMismatch
+	}
+
+	aead, err := x25519WrapAEAD(shared, ephemeral.Bytes(), i.key.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	fileKey, err := aead.Open(nil, nonce, slot.Body[32:], nil)
+	if err != nil {
+		return nil, ErrSlotMismatch
+	}
+
+	return fileKey, nil
+}
+
+func x25519WrapAEAD(shared, ephemeral, recipient []byte) (cipher.AEAD, error) {
+	salt := bytes.Join([][]byte{ephemeral, recipient}, nil)
+
+	wrapKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(x25519WrapInfo)), wrapKey); err != nil {
+		return nil, err
+	}
+
+	return chacha20poly1305.New(wrapKey)
+}
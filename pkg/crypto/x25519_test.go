@@ -0,0 +1,247 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestX25519Wrap(t *testing.T) {
+	alice, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipient, err := ParseRecipient(alice.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileKey := GenerateKey(32)
+	slot, err := recipient.Wrap(fileKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := newTestHeader(Version2)
+	if err := header.SetKeySlots([]KeySlot{*slot}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := EncryptHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecryptHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slots, err := decoded.KeySlots()
+	if err != nil || len(slots) != 1 {
+		t.Fatalf("get %d key slots (%v), expected: 1", len(slots), err)
+	}
+
+	key, err := alice.Unwrap(slots[0])
+	if err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+	if !bytes.Equal(key, fileKey) {
+		t.Fatalf("unwrapped key does not match")
+	}
+
+	if _, err := bob.Unwrap(slots[0]); !errors.Is(err, ErrSlotMismatch) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrSlotMismatch)
+	}
+
+	// key slots are not part of the data authenticated with the chunks
+	withSlots, err := AssociatedData(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.SetKeySlots(nil); err != nil {
+		t.Fatal(err)
+	}
+	withoutSlots, err := AssociatedData(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(withSlots, withoutSlots) {
+		t.Fatalf("associated data depends on key slots")
+	}
+}
+
+func TestParseIdentities(t *testing.T) {
+	identity, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type Case struct {
+		name string
+		file string
+		err  error
+	}
+
+	cases := []Case{
+		{
+			name: "identity with comments",
+			file: "# public key: " + identity.Recipient().String() + "\n\n" + identity.String() + "\n",
+			err:  nil,
+		},
+		{
+			name: "no identities",
+			file: "# empty\n",
+			err:  ErrInvalidIdentity,
+		},
+		{
+			name: "public key instead of identity",
+			file: identity.Recipient().String() + "\n",
+			err:  ErrInvalidIdentity,
+		},
+		{
+			name: "bad encoding",
+			file: IdentityPrefix + "!!!\n",
+			err:  ErrInvalidIdentity,
+		},
+	}
+
+	for _, c := range cases {
+		_, err := ParseIdentities(strings.NewReader(c.file))
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+	}
+
+	if _, err := ParseRecipient("age1abc"); !errors.Is(err, ErrInvalidRecipient) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrInvalidRecipient)
+	}
+}
+
+func TestPasswordSlot(t *testing.T) {
+	params := &KDFParams{KDF: KDFPBKDF2, Time: 1000}
+	fileKey := GenerateKey(32)
+
+	slot, err := WrapWithPassword([]byte("ops"), fileKey, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := slot.KDFParams()
+	if err != nil || *got != *params {
+		t.Fatalf("get kdf params: %v (%v), expected: %v", got, err, params)
+	}
+
+	key, err := UnwrapWithPassword(*slot, []byte("ops"), DefaultKDFLimits)
+	if err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+	if !bytes.Equal(key, fileKey) {
+		t.Fatalf("unwrapped key does not match")
+	}
+
+	if _, err := UnwrapWithPassword(*slot, []byte("ci"), DefaultKDFLimits); !errors.Is(err, ErrSlotMismatch) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrSlotMismatch)
+	}
+
+	limits := KDFLimits{Iterations: params.Time - 1}
+	if _, err := UnwrapWithPassword(*slot, []byte("ops"), limits); !errors.Is(err, ErrKDFLimit) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrKDFLimit)
+	}
+
+	truncated := KeySlot{Type: SlotPassword, Body: slot.Body[:kdfParamsSize]}
+	if _, err := UnwrapWithPassword(truncated, []byte("ops"), DefaultKDFLimits); !errors.Is(err, ErrInvalidExtension) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrInvalidExtension)
+	}
+}
+
+func TestUnwrapKey(t *testing.T) {
+	fileKey := GenerateKey(32)
+
+	slot, err := WrapWithPassword([]byte("ops"), fileKey, &KDFParams{KDF: KDFPBKDF2, Time: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	costly, err := WrapWithPassword([]byte("ops"), fileKey, &KDFParams{KDF: KDFPBKDF2, Time: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := KeySlot{Type: SlotPassword, Body: slot.Body[:kdfParamsSize]}
+	limits := KDFLimits{Iterations: 1500}
+
+	type Case struct {
+		name     string
+		slots    []KeySlot
+		password string
+		index    int
+		err      error
+	}
+
+	cases := []Case{
+		{name: "match", slots: []KeySlot{*slot}, password: "ops", index: 0},
+		{name: "bad slot before the match", slots: []KeySlot{truncated, *slot}, password: "ops", index: 1},
+		{name: "costly slot before the match", slots: []KeySlot{*costly, *slot}, password: "ops", index: 1},
+		{name: "no match", slots: []KeySlot{*slot}, password: "ci", err: ErrNoMatchingSlot},
+		{name: "no match after a bad slot", slots: []KeySlot{truncated, *slot}, password: "ci", err: ErrInvalidExtension},
+		{name: "no match after a costly slot", slots: []KeySlot{*slot, *costly}, password: "ci", err: ErrKDFLimit},
+	}
+
+	for _, c := range cases {
+		key, index, err := UnwrapKey(c.slots, len(fileKey), [][]byte{[]byte(c.password)}, nil, limits)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+		if err != nil {
+			continue
+		}
+		if index != c.index || !bytes.Equal(key, fileKey) {
+			t.Fatalf("[%s] get slot %d, expected slot: %d", c.name, index, c.index)
+		}
+	}
+}
+
+func TestSetKeySlotsArea(t *testing.T) {
+	slot := KeySlot{Type: SlotX25519, Body: GenerateKey(80)}
+	header := newTestHeader(Version2)
+
+	size := func() int {
+		encHeader, err := EncryptHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(encHeader)
+	}
+
+	if err := header.SetKeySlots([]KeySlot{slot}); err != nil {
+		t.Fatal(err)
+	}
+	initial := size()
+
+	// the header keeps its size while the slots fit into the reserved area
+	for _, count := range []int{0, 1, 10, 40, 5} {
+		if err := header.SetKeySlots(slices.Repeat([]KeySlot{slot}, count)); err != nil {
+			t.Fatal(err)
+		}
+		if size() != initial {
+			t.Fatalf("[%d slots] get header size %d, expected: %d", count, size(), initial)
+		}
+
+		slots, err := header.KeySlots()
+		if err != nil || len(slots) != count {
+			t.Fatalf("[%d slots] get %d slots (%v)", count, len(slots), err)
+		}
+	}
+
+	if err := header.SetKeySlots(slices.Repeat([]KeySlot{slot}, 100)); err != nil {
+		t.Fatal(err)
+	}
+	// the area grows by a multiple of its size
+	if grown := size() - initial; grown <= 0 || grown%KeySlotsAreaSize != 0 {
+		t.Fatalf("[100 slots] header grew by %d bytes, expected a multiple of %d", grown, KeySlotsAreaSize)
+	}
+}
@@ -0,0 +1,75 @@
+// Package cryptool encrypts and decrypts streams in the .crpt format written
+// by the cryptool command, so files can be exchanged with it.
+package cryptool
+
+import (
+	"fmt"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+)
+
+const (
+	DefaultAlgorithm = algorithms.AlgAES256GCM
+
+	DefaultBlockSize = 1 << 20
+	MinBlockSize     = crypto.MinBlockSize
+	MaxBlockSize     = crypto.MaxBlockSize
+)
+
+// ValidateBlockSize reports whether blockSize is within the supported range.
+func ValidateBlockSize(blockSize int) error {
+	if blockSize < 0 {
+		return fmt.Errorf("%w: %d bytes", ErrInvalidBlockSize, blockSize)
+	}
+	return crypto.ValidateBlockSize(crypto.CurrentVersion, uint64(blockSize))
+}
+
+type EncryptOptions struct {
+	// Algorithm is one of the algorithms package names, DefaultAlgorithm if empty
+	Algorithm string
+	// BlockSize is the plaintext size of every chunk but the last one,
+	// DefaultBlockSize if zero
+	BlockSize int
+	// Passwords and Recipients unlock a random file key wrapped in the header
+	Passwords  [][]byte
+	Recipients []*crypto.Recipient
+	// KDF derives the wrap keys of Passwords, PBKDF2 defaults if nil
+	KDF *crypto.KDFParams
+	// Key is used as the file key instead of Passwords and Recipients
+	Key []byte
+	// Content marks what the plaintext is, crypto.ContentFile if zero
+	Content crypto.ContentType
+
+	// Jobs is the number of chunks Encrypt seals in parallel, GOMAXPROCS
+	// if zero
+	Jobs int
+	// Progress is called by Encrypt with the block size after every
+	// written chunk
+	Progress func(n int)
+}
+
+type DecryptOptions struct {
+	// Passwords and Identities are tried against the key slots of the header
+	Passwords  [][]byte
+	Identities []*crypto.Identity
+	// Key opens files encrypted with a raw key
+	Key []byte
+	// KDFLimits caps the key derivation cost read from the file,
+	// crypto.DefaultKDFLimits if nil
+	KDFLimits *crypto.KDFLimits
+
+	// Jobs is the number of chunks Decrypt opens in parallel, GOMAXPROCS
+	// if zero
+	Jobs int
+	// Progress is called by Decrypt with the block size after every
+	// written chunk
+	Progress func(n int)
+}
+
+func (opts DecryptOptions) kdfLimits() crypto.KDFLimits {
+	if opts.KDFLimits == nil {
+		return crypto.DefaultKDFLimits
+	}
+	return *opts.KDFLimits
+}
@@ -0,0 +1,341 @@
+package cryptool
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/stream"
+)
+
+var testKDF = &crypto.KDFParams{KDF: crypto.KDFPBKDF2, Time: 1000}
+
+func encrypt(t *testing.T, plaintext []byte, opts EncryptOptions) []byte {
+	t.Helper()
+
+	out := bytes.NewBuffer([]byte{})
+	w, err := NewEncryptWriter(out, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// uneven writes cross chunk boundaries
+	for len(plaintext) > 0 {
+		n := min(len(plaintext), 1000)
+		if _, err := w.Write(plaintext[:n]); err != nil {
+			t.Fatal(err)
+		}
+		plaintext = plaintext[n:]
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.Bytes()
+}
+
+func decrypt(ciphertext []byte, opts DecryptOptions) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(ciphertext), opts)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	identity, err := crypto.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := crypto.GenerateKey(32)
+
+	encOpts := []EncryptOptions{
+		{BlockSize: MinBlockSize, Passwords: [][]byte{[]byte("ops")}, Recipients: []*crypto.Recipient{identity.Recipient()}, KDF: testKDF},
+		{BlockSize: MinBlockSize, Algorithm: "chacha20-poly1305", Key: key},
+	}
+	decOpts := []DecryptOptions{
+		{Passwords: [][]byte{[]byte("wrong"), []byte("ops")}},
+		{Key: key},
+	}
+
+	for i := range encOpts {
+		for _, size := range []int{0, 1, MinBlockSize - 1, MinBlockSize, MinBlockSize + 1, 3*MinBlockSize + 5} {
+			plaintext := crypto.GenerateKey(size)
+
+			got, err := decrypt(encrypt(t, plaintext, encOpts[i]), decOpts[i])
+			if err != nil {
+				t.Fatalf("[%d bytes] get error: %v, expected error: %v", size, err, nil)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatalf("[%d bytes] decrypted data does not match", size)
+			}
+		}
+	}
+
+	ciphertext := encrypt(t, []byte("secret"), encOpts[0])
+	got, err := decrypt(ciphertext, DecryptOptions{Identities: []*crypto.Identity{identity}})
+	if err != nil || string(got) != "secret" {
+		t.Fatalf("get %q (%v), expected: %q", got, err, "secret")
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	opts := EncryptOptions{BlockSize: MinBlockSize, Passwords: [][]byte{[]byte("ops")}, KDF: testKDF}
+	plaintext := bytes.Repeat([]byte("a"), 2*MinBlockSize+8)
+	ciphertext := encrypt(t, plaintext, opts)
+	tagSize := 16
+
+	unclosed := bytes.NewBuffer([]byte{})
+	w, err := NewEncryptWriter(unclosed, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+
+	type Case struct {
+		name       string
+		ciphertext []byte
+		password   string
+		err        error
+	}
+
+	cases := []Case{
+		{
+			name:       "wrong password",
+			ciphertext: ciphertext,
+			password:   "ci",
+			err:        crypto.ErrNoMatchingSlot,
+		},
+		{
+			name:       "last chunk removed",
+			ciphertext: ciphertext[:len(ciphertext)-(8+tagSize)],
+			password:   "ops",
+			err:        stream.ErrTruncated,
+		},
+		{
+			name:       "writer not closed",
+			ciphertext: unclosed.Bytes(),
+			password:   "ops",
+			err:        stream.ErrTruncated,
+		},
+		{
+			name:       "modified chunk",
+			ciphertext: append(bytes.Clone(ciphertext[:len(ciphertext)-1]), ciphertext[len(ciphertext)-1]^1),
+			password:   "ops",
+			err:        stream.ErrChunkAuthentication,
+		},
+	}
+
+	for _, c := range cases {
+		_, err := decrypt(c.ciphertext, DecryptOptions{Passwords: [][]byte{[]byte(c.password)}})
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+	}
+
+	// a slot costlier than the limits is not opened at all
+	limits := crypto.KDFLimits{Iterations: testKDF.Time - 1}
+	_, err = decrypt(ciphertext, DecryptOptions{Passwords: [][]byte{[]byte("ops")}, KDFLimits: &limits})
+	if !errors.Is(err, crypto.ErrKDFLimit) {
+		t.Fatalf("[kdf limit] get error: %v, expected error: %v", err, crypto.ErrKDFLimit)
+	}
+
+	if _, err := w.Write(nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if _, err := w.Write([]byte("a")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("get error: %v, expected error: %v", err, ErrClosed)
+	}
+}
+
+// eofReaderAt returns io.EOF along with the last bytes, as ReadAt may.
+type eofReaderAt struct {
+	*bytes.Reader
+}
+
+func (r eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.Reader.ReadAt(p, off)
+	if err == nil && off+int64(n) == r.Size() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func TestDecryptReaderAt(t *testing.T) {
+	const block = MinBlockSize
+
+	opts := EncryptOptions{BlockSize: block, Passwords: [][]byte{[]byte("ops")}, KDF: testKDF}
+	decOpts := DecryptOptions{Passwords: [][]byte{[]byte("ops")}}
+
+	plaintext := crypto.GenerateKey(15*block + 1000)
+	ciphertext := encrypt(t, plaintext, opts)
+
+	ra, err := NewDecryptReaderAt(eofReaderAt{bytes.NewReader(ciphertext)}, int64(len(ciphertext)), decOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ra.Size() != int64(len(plaintext)) {
+		t.Fatalf("get size: %d, expected: %d", ra.Size(), len(plaintext))
+	}
+
+	type Case struct {
+		start, end int64
+	}
+
+	size := int64(len(plaintext))
+	cases := []Case{{0, size}, {0, 1}, {block - 1, block + 1}, {2*block + 3, 9 * block}, {size - 1, size}, {10 * block, 10 * block}}
+	for _, c := range cases {
+		got, err := io.ReadAll(io.NewSectionReader(ra, c.start, c.end-c.start))
+		if err != nil {
+			t.Fatalf("[%d:%d] get error: %v, expected error: %v", c.start, c.end, err, nil)
+		}
+		if !bytes.Equal(got, plaintext[c.start:c.end]) {
+			t.Fatalf("[%d:%d] decrypted data does not match", c.start, c.end)
+		}
+	}
+
+	buf := make([]byte, 10)
+	if n, err := ra.ReadAt(buf, size-5); n != 5 || err != io.EOF {
+		t.Fatalf("get %d bytes (%v), expected: 5 bytes (%v)", n, err, io.EOF)
+	}
+
+	// without the last chunk the file ends with a full chunk not marked as last
+	truncated := ciphertext[:len(ciphertext)-(1000+16)]
+	ra, err = NewDecryptReaderAt(bytes.NewReader(truncated), int64(len(truncated)), decOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ra.ReadAt(buf, ra.Size()-1); !errors.Is(err, stream.ErrTruncated) {
+		t.Fatalf("get error: %v, expected error: %v", err, stream.ErrTruncated)
+	}
+}
+
+func TestEncryptWriterAllocs(t *testing.T) {
+	w, err := NewEncryptWriter(io.Discard, EncryptOptions{BlockSize: MinBlockSize, Key: crypto.GenerateKey(32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunk := crypto.GenerateKey(MinBlockSize)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("Write allocates %v times per chunk, expected 0", allocs)
+	}
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	plaintext, err := os.ReadFile("testdata/v0.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// written by the original tool, before the format had a version: one
+	// chunk of the whole file, and chunks of 1000 bytes
+	files := []string{"testdata/v0-aes256-gcm.crpt", "testdata/v0-chacha20-poly1305.crpt"}
+
+	for _, name := range files {
+		ciphertext, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := decrypt(ciphertext, DecryptOptions{Passwords: [][]byte{[]byte("password")}})
+		if err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", name, err, nil)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("[%s] decrypted data does not match", name)
+		}
+
+		ra, err := NewDecryptReaderAt(bytes.NewReader(ciphertext), int64(len(ciphertext)), DecryptOptions{Passwords: [][]byte{[]byte("password")}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = io.ReadAll(io.NewSectionReader(ra, 990, 1020))
+		if err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", name, err, nil)
+		}
+		if !bytes.Equal(got, plaintext[990:2010]) {
+			t.Fatalf("[%s] decrypted range does not match", name)
+		}
+
+		// the header is not authenticated, a wrong password fails the first chunk
+		_, err = decrypt(ciphertext, DecryptOptions{Passwords: [][]byte{[]byte("wrong")}})
+		if !errors.Is(err, stream.ErrChunkAuthentication) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", name, err, stream.ErrChunkAuthentication)
+		}
+	}
+}
+
+func TestDecryptLegacyBlockSize(t *testing.T) {
+	plaintext, err := os.ReadFile("testdata/v0.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := os.ReadFile("testdata/v0-aes256-gcm.crpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the v0 header is not authenticated, the block size after the magic
+	// number and the algorithm can be raised without failing the file
+	forged := bytes.Clone(ciphertext)
+	binary.LittleEndian.PutUint64(forged[6:], crypto.MaxLegacyBlockSize)
+
+	opts := DecryptOptions{Passwords: [][]byte{[]byte("password")}}
+
+	type Case struct {
+		name    string
+		decrypt func() ([]byte, error)
+	}
+
+	cases := []Case{
+		{name: "reader", decrypt: func() ([]byte, error) {
+			return decrypt(forged, opts)
+		}},
+		{name: "parallel", decrypt: func() ([]byte, error) {
+			out := bytes.NewBuffer([]byte{})
+			err := Decrypt(context.Background(), out, bytes.NewReader(forged), opts)
+			return out.Bytes(), err
+		}},
+		{name: "reader at", decrypt: func() ([]byte, error) {
+			ra, err := NewDecryptReaderAt(bytes.NewReader(forged), int64(len(forged)), opts)
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(io.NewSectionReader(ra, 0, ra.Size()))
+		}},
+	}
+
+	for _, c := range cases {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+
+		got, err := c.decrypt()
+		if err != nil {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, nil)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("[%s] decrypted data does not match", c.name)
+		}
+
+		runtime.ReadMemStats(&after)
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Fatalf("[%s] allocated %d bytes for a %d byte file", c.name, allocated, len(forged))
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package cryptool
+
+import (
+	"errors"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+)
+
+var (
+	ErrNoKey            = errors.New("a password, a recipient or a key is required")
+	ErrConflictingKeys  = errors.New("a key cannot be combined with passwords or recipients")
+	ErrKeyRequired      = errors.New("file is encrypted with a raw key, a key is required")
+	ErrClosed           = errors.New("write to a closed encrypt writer")
+	ErrNegativeOffset   = errors.New("negative offset")
+	ErrInvalidBlockSize = crypto.ErrInvalidBlockSize
+
+	// Errors of Decrypt and the decrypt readers caused by a wrong password
+	// or key wrap ErrWrongKey, errors caused by a damaged file wrap
+	// ErrCorrupted.
+	ErrWrongKey  = errors.New("wrong password or key")
+	ErrCorrupted = errors.New("file is corrupted")
+	ErrCancelled = errors.New("operation cancelled")
+)
@@ -0,0 +1,5 @@
+//go:build !race
+
+package cryptool
+
+const raceEnabled = false
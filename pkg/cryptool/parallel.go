@@ -0,0 +1,201 @@
+package cryptool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DimaKropachev/cryptool/pkg/file"
+)
+
+// errSkipped marks chunks after a failed one, it is never returned.
+var errSkipped = errors.New("skipped after a failed chunk")
+
+type chunkResult struct {
+	index uint64
+	data  []byte
+	buf   *[]byte
+	err   error
+}
+
+// processChunks runs process on the chunks from content with a pool of jobs
+// workers and writes the results to w in chunk order. At most 2*jobs chunks
+// are held in memory, a slow chunk stops the reader instead of letting
+// finished chunks pile up. jobs below one means GOMAXPROCS.
+//
+// process appends its result to dst, a buffer reused once the result is
+// written. Chunks are released after process returns, so in steady state no
+// chunk allocates.
+//
+// progress, if not nil, is called with step after every written chunk.
+//
+// The error of the first failing chunk is returned after all chunks before it
+// are written. Chunks after a failed one are skipped. When ctx is done an
+// error wrapping ErrCancelled is returned. Every worker has stopped by the
+// time processChunks returns, the reader of content is expected to stop on
+// the same ctx.
+func processChunks(
+	ctx context.Context,
+	content <-chan file.Content,
+	errs <-chan error,
+	jobs int,
+	process func(dst []byte, c file.Content) ([]byte, error),
+	w io.Writer,
+	progress func(n int),
+	step int,
+) error {
+	if jobs < 1 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	if progress == nil {
+		progress = func(int) {}
+	}
+
+	var (
+		work    = make(chan file.Content)
+		results = make(chan chunkResult)
+		window  = make(chan struct{}, 2*jobs)
+		stop    = make(chan struct{})
+		out     = file.NewBufferPool(0)
+	)
+
+	go func() {
+		defer close(work)
+
+		for {
+			// the reader may be stuck in a read of a pipe, do not wait for it
+			var c file.Content
+			select {
+			case chunk, ok := <-content:
+				if !ok {
+					return
+				}
+				c = chunk
+			case <-stop:
+				return
+			}
+
+			select {
+			case window <- struct{}{}:
+			case <-stop:
+				c.Release()
+				return
+			}
+
+			select {
+			case work <- c:
+			case <-stop:
+				c.Release()
+				return
+			}
+		}
+	}()
+
+	// index of the first failed chunk known so far
+	failed := &atomic.Uint64{}
+	failed.Store(math.MaxUint64)
+
+	wg := &sync.WaitGroup{}
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for c := range work {
+				var (
+					data []byte
+					buf  *[]byte
+					err  error
+				)
+				if c.Index > failed.Load() {
+					err = errSkipped
+				} else {
+					buf = out.Get()
+					data, err = process(*buf, c)
+				}
+				c.Release()
+
+				select {
+				case results <- chunkResult{index: c.Index, data: data, buf: buf, err: err}:
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	fail := func(err error) error {
+		close(stop)
+		for range results {
+		}
+		return err
+	}
+
+	pending := map[uint64]chunkResult{}
+	next := uint64(0)
+
+	for {
+		select {
+		case r, ok := <-results:
+			if !ok {
+				// the reader stops early only when ctx is done
+				if ctx.Err() != nil {
+					return Cancelled(ctx)
+				}
+				return nil
+			}
+			pending[r.index] = r
+			if r.err != nil && r.index < failed.Load() {
+				failed.Store(r.index)
+			}
+
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+
+				if r.err != nil {
+					return fail(r.err)
+				}
+
+				if _, err := w.Write(r.data); err != nil {
+					return fail(err)
+				}
+				// keep the capacity the result has grown to
+				*r.buf = r.data[:0]
+				out.Put(r.buf)
+
+				progress(step)
+				<-window
+				next++
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if err != nil {
+				return fail(fmt.Errorf("error reading file: %w", err))
+			}
+		case <-ctx.Done():
+			return fail(Cancelled(ctx))
+		}
+	}
+}
+
+// Cancelled wraps the reason ctx is done with ErrCancelled.
+func Cancelled(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrCancelled, context.Cause(ctx))
+}
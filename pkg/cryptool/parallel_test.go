@@ -0,0 +1,268 @@
+package cryptool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DimaKropachev/cryptool/internal/testutil"
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/file"
+)
+
+func TestProcessChunks(t *testing.T) {
+	errChunk := errors.New("chunk error")
+
+	type Case struct {
+		name   string
+		chunks int
+		jobs   int
+		failAt int
+		err    error
+	}
+
+	cases := []Case{
+		{name: "one job", chunks: 50, jobs: 1, failAt: -1},
+		{name: "many jobs", chunks: 200, jobs: 8, failAt: -1},
+		{name: "failing chunk", chunks: 200, jobs: 8, failAt: 120, err: errChunk},
+	}
+
+	for _, c := range cases {
+		data := make([]byte, c.chunks)
+		for i := range data {
+			data[i] = byte(i)
+		}
+
+		content, errs := file.ReadDecrypted(context.Background(), bytes.NewReader(data), file.NewBufferPool(1))
+
+		// chunks finish out of order
+		process := func(dst []byte, chunk file.Content) ([]byte, error) {
+			time.Sleep(time.Duration(rand.IntN(100)) * time.Microsecond)
+			if int(chunk.Index) == c.failAt {
+				return nil, errChunk
+			}
+			return append(dst, chunk.Buf...), nil
+		}
+
+		written := 0
+		progress := func(n int) { written += n }
+
+		out := bytes.NewBuffer([]byte{})
+		err := processChunks(context.Background(), content, errs, c.jobs, process, out, progress, 1)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+
+		if c.err == nil && !bytes.Equal(out.Bytes(), data) {
+			t.Fatalf("[%s] chunks are written out of order", c.name)
+		}
+		if c.err != nil && !bytes.Equal(out.Bytes(), data[:c.failAt]) {
+			t.Fatalf("[%s] get %d bytes before the error, expected: %d", c.name, out.Len(), c.failAt)
+		}
+		if written != out.Len() {
+			t.Fatalf("[%s] get progress: %d, expected: %d", c.name, written, out.Len())
+		}
+	}
+}
+
+func TestProcessChunksStopsAfterFailure(t *testing.T) {
+	const (
+		chunks = 10000
+		jobs   = 4
+		failAt = 10
+	)
+
+	content, errs := file.ReadDecrypted(context.Background(), bytes.NewReader(make([]byte, chunks)), file.NewBufferPool(1))
+
+	processed := &atomic.Int64{}
+	process := func(dst []byte, chunk file.Content) ([]byte, error) {
+		processed.Add(1)
+		if chunk.Index == failAt {
+			return nil, errors.New("bad chunk")
+		}
+		return append(dst, chunk.Buf...), nil
+	}
+
+	if err := processChunks(context.Background(), content, errs, jobs, process, io.Discard, nil, 1); err == nil {
+		t.Fatalf("get error: %v, expected error: %v", err, "bad chunk")
+	}
+
+	// no more chunks than the reorder window are read past the written ones
+	if n := processed.Load(); n > failAt+2*jobs {
+		t.Fatalf("get %d processed chunks, expected at most: %d", n, failAt+2*jobs)
+	}
+}
+
+func TestProcessChunksCancelled(t *testing.T) {
+	errChunk := errors.New("chunk error")
+
+	type Case struct {
+		name     string
+		cancelAt int
+		failAt   int
+		err      error
+	}
+
+	cases := []Case{
+		{name: "cancelled", cancelAt: 10, failAt: -1, err: ErrCancelled},
+		{name: "failing chunk", cancelAt: -1, failAt: 10, err: errChunk},
+		{name: "finished", cancelAt: -1, failAt: -1},
+	}
+
+	for _, c := range cases {
+		before := runtime.NumGoroutine()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		content, errs := file.ReadDecrypted(ctx, bytes.NewReader(make([]byte, 10000)), file.NewBufferPool(1))
+
+		process := func(dst []byte, chunk file.Content) ([]byte, error) {
+			switch int(chunk.Index) {
+			case c.cancelAt:
+				cancel()
+			case c.failAt:
+				return nil, errChunk
+			}
+			return append(dst, chunk.Buf...), nil
+		}
+
+		err := processChunks(ctx, content, errs, 4, process, bytes.NewBuffer([]byte{}), nil, 1)
+		cancel()
+
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+		testutil.CheckGoroutines(t, c.name, before)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := crypto.GenerateKey(32)
+
+	for _, size := range []int{0, 1, MinBlockSize, 3*MinBlockSize + 100} {
+		plaintext := crypto.GenerateKey(size)
+
+		ciphertext := bytes.NewBuffer([]byte{})
+		opts := EncryptOptions{BlockSize: MinBlockSize, Key: key, Jobs: 4}
+		if err := Encrypt(context.Background(), ciphertext, bytes.NewReader(plaintext), opts); err != nil {
+			t.Fatal(err)
+		}
+
+		// the parallel and the streaming API write the same format
+		got, err := decrypt(ciphertext.Bytes(), DecryptOptions{Key: key})
+		if err != nil {
+			t.Fatalf("[%d bytes] get error: %v, expected error: %v", size, err, nil)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("[%d bytes] decrypted data does not match", size)
+		}
+
+		out := bytes.NewBuffer([]byte{})
+		if err := Decrypt(context.Background(), out, bytes.NewReader(ciphertext.Bytes()), DecryptOptions{Key: key, Jobs: 4}); err != nil {
+			t.Fatalf("[%d bytes] get error: %v, expected error: %v", size, err, nil)
+		}
+		if !bytes.Equal(out.Bytes(), plaintext) {
+			t.Fatalf("[%d bytes] decrypted data does not match", size)
+		}
+	}
+}
+
+func TestDecryptClassifiesErrors(t *testing.T) {
+	opts := EncryptOptions{BlockSize: MinBlockSize, Passwords: [][]byte{[]byte("ops")}, KDF: testKDF}
+	ciphertext := encrypt(t, bytes.Repeat([]byte("a"), 2*MinBlockSize+8), opts)
+
+	type Case struct {
+		name       string
+		ciphertext []byte
+		password   string
+		err        error
+	}
+
+	cases := []Case{
+		{name: "wrong password", ciphertext: ciphertext, password: "ci", err: ErrWrongKey},
+		{name: "modified header", ciphertext: append([]byte("CRPX"), ciphertext[4:]...), password: "ops", err: ErrCorrupted},
+		{name: "last chunk removed", ciphertext: ciphertext[:len(ciphertext)-(8+16)], password: "ops", err: ErrCorrupted},
+		{name: "modified chunk", ciphertext: append(bytes.Clone(ciphertext[:len(ciphertext)-1]), ciphertext[len(ciphertext)-1]^1), password: "ops", err: ErrCorrupted},
+	}
+
+	for _, c := range cases {
+		err := Decrypt(context.Background(), io.Discard, bytes.NewReader(c.ciphertext), DecryptOptions{Passwords: [][]byte{[]byte(c.password)}})
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+	}
+}
+
+// chunkAllocs returns the allocations per chunk of run, leaving out the fixed
+// cost of setting up the stream.
+func chunkAllocs(run func(chunks int)) float64 {
+	const chunks = 64
+
+	few := testing.AllocsPerRun(5, func() { run(chunks) })
+	many := testing.AllocsPerRun(5, func() { run(2 * chunks) })
+
+	return (many - few) / chunks
+}
+
+func TestEncryptDecryptAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("buffers are not reused under the race detector")
+	}
+
+	const blockSize = MinBlockSize
+
+	password := []byte("password")
+	encOpts := EncryptOptions{BlockSize: blockSize, Passwords: [][]byte{password}, KDF: testKDF, Jobs: 4}
+	decOpts := DecryptOptions{Passwords: [][]byte{password}, Jobs: 4}
+
+	plaintext := crypto.GenerateKey(2 * 64 * blockSize)
+	encrypted := map[int][]byte{}
+
+	encryptChunks := func(chunks int) {
+		out := bytes.NewBuffer(make([]byte, 0, len(plaintext)*2))
+		if err := Encrypt(context.Background(), out, bytes.NewReader(plaintext[:chunks*blockSize]), encOpts); err != nil {
+			t.Fatal(err)
+		}
+		encrypted[chunks] = out.Bytes()
+	}
+
+	decryptChunks := func(chunks int) {
+		if err := Decrypt(context.Background(), io.Discard, bytes.NewReader(encrypted[chunks]), decOpts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := chunkAllocs(encryptChunks); n >= 1 {
+		t.Fatalf("Encrypt allocates %v times per chunk, expected 0", n)
+	}
+	if n := chunkAllocs(decryptChunks); n >= 1 {
+		t.Fatalf("Decrypt allocates %v times per chunk, expected 0", n)
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	const size = 64 << 20
+
+	plaintext := crypto.GenerateKey(size)
+	key := crypto.GenerateKey(32)
+
+	for _, jobs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			opts := EncryptOptions{Key: key, Jobs: jobs}
+
+			b.SetBytes(size)
+			b.ReportAllocs()
+			for range b.N {
+				if err := Encrypt(context.Background(), io.Discard, bytes.NewReader(plaintext), opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package cryptool
+
+// sync.Pool drops buffers on purpose under the race detector
+const raceEnabled = true
@@ -0,0 +1,271 @@
+package cryptool
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/stream"
+	"github.com/DimaKropachev/cryptool/pkg/file"
+)
+
+// DecryptReader returns the plaintext of an encrypted stream. Every chunk is
+// authenticated before any of its bytes are returned, a truncated or modified
+// stream makes Read fail.
+type DecryptReader struct {
+	r         *bufio.Reader
+	h         *openedHeader
+	buf       []byte
+	out       []byte
+	plaintext []byte
+	index     uint64
+	done      bool
+	err       error
+}
+
+// NewDecryptReader reads the header from r, unlocks the file key and checks
+// the header tag.
+func NewDecryptReader(r io.Reader, opts DecryptOptions) (*DecryptReader, error) {
+	h, err := openHeader(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the chunk size of a v0 header is not authenticated, its buffer grows
+	// with the chunk read into it
+	size := h.chunkSize()
+	if h.header.Version == crypto.Version0 {
+		size = min(size, crypto.MinBlockSize)
+	}
+
+	return &DecryptReader{
+		r:   bufio.NewReader(r),
+		h:   h,
+		buf: make([]byte, size),
+	}, nil
+}
+
+// Decrypt reads the encrypted stream src and writes the plaintext to dst.
+// Chunks are opened by opts.Jobs workers and written in order, like Encrypt
+// seals them. Only authenticated chunks are written, but the chunks before a
+// failed one are. When ctx is done an error wrapping ErrCancelled is
+// returned.
+func Decrypt(ctx context.Context, dst io.Writer, src io.Reader, opts DecryptOptions) error {
+	h, err := openHeader(src, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := opts.Jobs
+	// v0 chunks may be as large as the whole file, open one at a time
+	if h.header.BlockSize > crypto.MaxBlockSize {
+		jobs = 1
+	}
+
+	pool := file.NewBufferPool(h.chunkSize())
+	// the chunk size of a v0 header is not authenticated
+	if h.header.Version == crypto.Version0 {
+		pool = file.NewGrowingBufferPool(h.chunkSize())
+	}
+
+	content, errs, err := file.ReadEncryptedFile(ctx, src, h.dec.ChunkNonceSize(), pool)
+	if err != nil {
+		return fmt.Errorf("error reading input file: %w", err)
+	}
+
+	open := func(dst []byte, ciphertext file.Content) ([]byte, error) {
+		plaintext, err := h.dec.Open(dst, ciphertext.Index, ciphertext.Nonce, ciphertext.Buf, ciphertext.Last)
+		if err != nil {
+			return nil, h.chunkError(ciphertext.Index, err)
+		}
+		return plaintext, nil
+	}
+
+	return processChunks(ctx, content, errs, jobs, open, dst, opts.Progress, int(h.header.BlockSize))
+}
+
+// openedHeader is an authenticated header and the decryptor of its chunks.
+type openedHeader struct {
+	header *crypto.Header
+	alg    algorithms.CipherAlgorithm
+	dec    *stream.Decryptor
+	// size of the header and the header tag
+	size int64
+}
+
+// chunkSize returns the size of every stored chunk but the last one.
+func (h *openedHeader) chunkSize() int {
+	return h.dec.ChunkNonceSize() + int(h.header.BlockSize) + h.alg.GetTagSize()
+}
+
+// chunkError wraps an error opening the chunk at index with ErrCorrupted and
+// the offset of the chunk in the file.
+func (h *openedHeader) chunkError(index uint64, err error) error {
+	// a v0 header has no tag, a wrong key fails the first chunk
+	if h.header.Version == crypto.Version0 && index == 0 {
+		return fmt.Errorf("%w: %w", ErrWrongKey, err)
+	}
+
+	offset := h.size + int64(index)*int64(h.chunkSize())
+	return fmt.Errorf("%w at offset %d: %w", ErrCorrupted, offset, err)
+}
+
+// openHeader reads the header, unlocks the file key and checks the header
+// tag.
+func openHeader(r io.Reader, opts DecryptOptions) (*openedHeader, error) {
+	header, err := crypto.DecryptHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
+	}
+
+	keySize, err := algorithms.KeySize(int(header.AlgID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
+	}
+
+	mode, err := header.KeyMode()
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
+	}
+
+	encHeader, err := crypto.EncryptHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := fileKeys(header, keySize, opts)
+	if errors.Is(err, crypto.ErrNoMatchingSlot) {
+		return nil, fmt.Errorf("%w: %w", ErrWrongKey, err)
+	}
+	// key slots are not covered by the header tag
+	if errors.Is(err, crypto.ErrInvalidExtension) || errors.Is(err, crypto.ErrInvalidKDFParams) {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	headerTag := []byte(nil)
+	for _, key := range keys {
+		alg, err := algorithms.CreateAlgorithmByID(int(header.AlgID), key)
+		if err != nil {
+			return nil, err
+		}
+
+		dec, err := stream.NewDecryptor(alg, header)
+		if err != nil {
+			return nil, fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
+		}
+
+		if headerTag == nil {
+			headerTag = make([]byte, dec.HeaderTagSize())
+			if _, err := io.ReadFull(r, headerTag); err != nil {
+				return nil, fmt.Errorf("%w: error reading header: %w", ErrCorrupted, err)
+			}
+		}
+
+		if err := dec.OpenHeader(headerTag); err != nil {
+			continue
+		}
+
+		return &openedHeader{
+			header: header,
+			alg:    alg,
+			dec:    dec,
+			size:   int64(len(encHeader) + len(headerTag)),
+		}, nil
+	}
+
+	// a key from a slot is known to be right, so the header was modified
+	if mode == crypto.KeyModeSlots {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupted, crypto.ErrHeaderAuthentication)
+	}
+	return nil, fmt.Errorf("%w: %w", ErrWrongKey, crypto.ErrHeaderAuthentication)
+}
+
+// fileKeys returns the candidate file keys, the right one opens the header
+// tag. v0 headers have no tag, so the first password is used.
+func fileKeys(header *crypto.Header, keySize int, opts DecryptOptions) ([][]byte, error) {
+	mode, err := header.KeyMode()
+	if err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case crypto.KeyModeRawKey:
+		if opts.Key == nil {
+			return nil, ErrKeyRequired
+		}
+		return [][]byte{opts.Key}, nil
+	case crypto.KeyModeSlots:
+		slots, err := header.KeySlots()
+		if err != nil {
+			return nil, err
+		}
+
+		key, _, err := crypto.UnwrapKey(slots, keySize, opts.Passwords, opts.Identities, opts.kdfLimits())
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{key}, nil
+	default:
+		keys := [][]byte{}
+		for _, password := range opts.Passwords {
+			key, err := crypto.DeriveKey(password, header.Salt, keySize, crypto.LegacyKDFParams())
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			return nil, ErrNoKey
+		}
+		return keys, nil
+	}
+}
+
+func (r *DecryptReader) Read(p []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+
+		r.plaintext, r.err = r.next()
+	}
+
+	n := copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+	return n, nil
+}
+
+// next opens the next chunk. A chunk is the last one if nothing follows it.
+func (r *DecryptReader) next() ([]byte, error) {
+	n, last, err := file.ReadChunk(r.r, &r.buf, r.h.chunkSize())
+	if err != nil {
+		return nil, err
+	}
+
+	chunk := r.buf[:n]
+	nonceSize := min(r.h.dec.ChunkNonceSize(), n)
+
+	// the previous chunk is fully read, its buffer is reused
+	plaintext, err := r.h.dec.Open(r.out[:0], r.index, chunk[:nonceSize], chunk[nonceSize:], last)
+	if err != nil {
+		return nil, r.h.chunkError(r.index, err)
+	}
+	r.out = plaintext
+
+	r.index++
+	r.done = last
+	return plaintext, nil
+}
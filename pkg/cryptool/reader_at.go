@@ -0,0 +1,134 @@
+package cryptool
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/stream"
+)
+
+// DecryptReaderAt decrypts any part of an encrypted file. Chunks have a fixed
+// size, so only the chunks holding the requested bytes are read and opened.
+type DecryptReaderAt struct {
+	r      io.ReaderAt
+	h      *openedHeader
+	data   int64
+	chunks uint64
+	size   int64
+
+	// the last opened chunk, sequential reads usually hit it again. Both
+	// buffers are reused for every chunk.
+	mu         sync.Mutex
+	cached     uint64
+	cachedData []byte
+	ciphertext []byte
+}
+
+// NewDecryptReaderAt opens the encrypted file of the given size read through r.
+func NewDecryptReaderAt(r io.ReaderAt, size int64, opts DecryptOptions) (*DecryptReaderAt, error) {
+	h, err := openHeader(io.NewSectionReader(r, 0, size), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.header.BlockSize == 0 {
+		return nil, fmt.Errorf("%w: error reading header: %w: block size is zero", ErrCorrupted, crypto.ErrInvalidExtension)
+	}
+
+	ra := &DecryptReaderAt{
+		r:      r,
+		h:      h,
+		data:   size - h.size,
+		cached: ^uint64(0),
+	}
+
+	chunkSize := int64(h.chunkSize())
+	overhead := chunkSize - int64(h.header.BlockSize)
+
+	if ra.data > 0 {
+		ra.chunks = uint64((ra.data + chunkSize - 1) / chunkSize)
+
+		last := ra.data - int64(ra.chunks-1)*chunkSize
+		if last < overhead {
+			return nil, fmt.Errorf("%w: %w", ErrCorrupted, &stream.ChunkError{Index: ra.chunks - 1, Err: stream.ErrTruncated})
+		}
+		ra.size = ra.data - int64(ra.chunks)*overhead
+	} else if h.header.Version != crypto.Version0 {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupted, &stream.ChunkError{Index: 0, Err: stream.ErrTruncated})
+	}
+
+	return ra, nil
+}
+
+// Size returns the size of the plaintext.
+func (ra *DecryptReaderAt) Size() int64 {
+	return ra.size
+}
+
+func (ra *DecryptReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+
+	blockSize := int64(ra.h.header.BlockSize)
+
+	n := 0
+	for n < len(p) && off < ra.size {
+		index := uint64(off / blockSize)
+
+		m, err := ra.readChunk(p[n:], index, off-int64(index)*blockSize)
+		if err != nil {
+			return n, err
+		}
+		n += m
+		off += int64(m)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// readChunk copies the plaintext of the chunk starting at off into p. The
+// copy is made under the lock, the cached chunk is overwritten by the next one.
+func (ra *DecryptReaderAt) readChunk(p []byte, index uint64, off int64) (int, error) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	if ra.cached != index {
+		if err := ra.openChunk(index); err != nil {
+			return 0, err
+		}
+	}
+
+	return copy(p, ra.cachedData[off:]), nil
+}
+
+func (ra *DecryptReaderAt) openChunk(index uint64) error {
+	ra.cached = ^uint64(0)
+
+	chunkSize := int64(ra.h.chunkSize())
+	offset := int64(index) * chunkSize
+
+	// a v0 header may claim chunks larger than the file
+	if ra.ciphertext == nil {
+		ra.ciphertext = make([]byte, min(chunkSize, ra.data))
+	}
+	buf := ra.ciphertext[:min(chunkSize, ra.data-offset)]
+	// the last chunk may come with io.EOF
+	if n, err := ra.r.ReadAt(buf, ra.h.size+offset); n < len(buf) {
+		return err
+	}
+
+	nonceSize := ra.h.dec.ChunkNonceSize()
+	plaintext, err := ra.h.dec.Open(ra.cachedData[:0], index, buf[:nonceSize], buf[nonceSize:], index == ra.chunks-1)
+	if err != nil {
+		return ra.h.chunkError(index, err)
+	}
+
+	ra.cached, ra.cachedData = index, plaintext
+	return nil
+}
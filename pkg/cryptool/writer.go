@@ -0,0 +1,210 @@
+package cryptool
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/algorithms"
+	"github.com/DimaKropachev/cryptool/pkg/crypto/stream"
+	"github.com/DimaKropachev/cryptool/pkg/file"
+)
+
+// EncryptWriter encrypts everything written to it. Close must be called to
+// write the final chunk, without it the output is reported as truncated.
+type EncryptWriter struct {
+	w     io.Writer
+	enc   *stream.Encryptor
+	buf   []byte
+	out   []byte
+	index uint64
+	err   error
+}
+
+// NewEncryptWriter writes the header to w and returns a writer for the
+// plaintext.
+func NewEncryptWriter(w io.Writer, opts EncryptOptions) (*EncryptWriter, error) {
+	enc, blockSize, err := writeHeader(w, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptWriter{
+		w:   w,
+		enc: enc,
+		buf: make([]byte, 0, blockSize),
+	}, nil
+}
+
+// Encrypt writes the header and the chunks of everything read from src to
+// dst. Chunks are sealed by opts.Jobs workers and written in order, their
+// buffers are reused, so in steady state no chunk allocates. When ctx is done
+// an error wrapping ErrCancelled is returned.
+func Encrypt(ctx context.Context, dst io.Writer, src io.Reader, opts EncryptOptions) error {
+	enc, blockSize, err := writeHeader(dst, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	content, errs := file.ReadDecrypted(ctx, src, file.NewBufferPool(blockSize))
+
+	seal := func(dst []byte, plaintext file.Content) ([]byte, error) {
+		return enc.Seal(dst, plaintext.Index, plaintext.Buf, plaintext.Last)
+	}
+
+	return processChunks(ctx, content, errs, opts.Jobs, seal, dst, opts.Progress, blockSize)
+}
+
+// writeHeader writes the header and the header tag for opts to w. It returns
+// the encryptor of the chunks and their block size.
+func writeHeader(w io.Writer, opts EncryptOptions) (*stream.Encryptor, int, error) {
+	if opts.Algorithm == "" {
+		opts.Algorithm = DefaultAlgorithm
+	}
+	if opts.BlockSize == 0 {
+		opts.BlockSize = DefaultBlockSize
+	}
+	if err := ValidateBlockSize(opts.BlockSize); err != nil {
+		return nil, 0, err
+	}
+	if opts.KDF == nil {
+		opts.KDF = crypto.DefaultKDFParams(crypto.KDFPBKDF2)
+	}
+
+	algID, err := algorithms.IDByName(opts.Algorithm)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	keySize, err := algorithms.KeySize(algID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	key, mode, slots, err := newFileKey(keySize, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	alg, err := algorithms.CreateAlgorithmByID(algID, key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	header := crypto.NewHeader(algID, opts.BlockSize, 0, alg.GetNonceSize(), nil, crypto.GenerateNoncePrefix(alg.GetNonceSize()))
+	header.SetKeyMode(mode)
+	if mode == crypto.KeyModeSlots {
+		if err := header.SetKeySlots(slots); err != nil {
+			return nil, 0, err
+		}
+	}
+	header.SetContentType(opts.Content)
+
+	encHeader, err := crypto.EncryptHeader(header)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	enc, err := stream.NewEncryptor(alg, header)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	headerTag, err := enc.SealHeader()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err := w.Write(encHeader); err != nil {
+		return nil, 0, fmt.Errorf("error writing the header: %w", err)
+	}
+	if _, err := w.Write(headerTag); err != nil {
+		return nil, 0, fmt.Errorf("error writing the header: %w", err)
+	}
+
+	return enc, opts.BlockSize, nil
+}
+
+func newFileKey(keySize int, opts EncryptOptions) ([]byte, crypto.KeyMode, []crypto.KeySlot, error) {
+	if opts.Key != nil {
+		if len(opts.Passwords) != 0 || len(opts.Recipients) != 0 {
+			return nil, 0, nil, ErrConflictingKeys
+		}
+		return opts.Key, crypto.KeyModeRawKey, nil, nil
+	}
+
+	if len(opts.Passwords) == 0 && len(opts.Recipients) == 0 {
+		return nil, 0, nil, ErrNoKey
+	}
+
+	key := crypto.GenerateKey(keySize)
+
+	slots, err := crypto.WrapKey(key, opts.Passwords, opts.Recipients, opts.KDF)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	return key, crypto.KeyModeSlots, slots, nil
+}
+
+func (w *EncryptWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n := 0
+	for len(p) > 0 {
+		// more data follows, so a full chunk is not the last one
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+		}
+
+		m := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+
+	return n, nil
+}
+
+// Close writes the final chunk. It does not close the underlying writer.
+func (w *EncryptWriter) Close() error {
+	if w.err != nil {
+		if w.err == ErrClosed {
+			return nil
+		}
+		return w.err
+	}
+
+	if err := w.flush(true); err != nil {
+		return err
+	}
+
+	w.err = ErrClosed
+	return nil
+}
+
+func (w *EncryptWriter) flush(last bool) error {
+	ciphertext, err := w.enc.Seal(w.out[:0], w.index, w.buf, last)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	w.out = ciphertext
+
+	if _, err := w.w.Write(ciphertext); err != nil {
+		w.err = err
+		return err
+	}
+
+	w.index++
+	w.buf = w.buf[:0]
+	return nil
+}
@@ -47,22 +47,24 @@ func (ds *DirectoryScanner) readDirectory(path string) error {
 			currPath := path + string(filepath.Separator) + obj.Name()
 			currName := strings.TrimPrefix(currPath, ds.BasePath)
 
+			// symlinks are not followed, Info describes the link itself
+			info, err := obj.Info()
+			if err != nil {
+				return fmt.Errorf("failed get information from <%s>", currPath)
+			}
+
+			// directories precede their files, so empty ones are kept too
+			ds.Files = append(ds.Files, &models.File{
+				Name: currName,
+				Info: info,
+				Path: currPath,
+			})
+
 			if obj.IsDir() {
 				err = ds.readDirectory(currPath)
 				if err != nil {
 					return err
 				}
-			} else {
-				info, err := obj.Info()
-				if err != nil {
-					return fmt.Errorf("failed get information from <%s>", currPath)
-				}
-
-				ds.Files = append(ds.Files, &models.File{
-					Name: currName,
-					Info: info,
-					Path: currPath,
-				})
 			}
 		}
 	} else {
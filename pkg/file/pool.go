@@ -0,0 +1,51 @@
+package file
+
+import "sync"
+
+// BufferPool reuses chunk buffers, so a steady stream of chunks is read and
+// processed without allocating.
+type BufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+// NewBufferPool returns a pool of buffers of size bytes. Buffers of a pool
+// with size zero grow with use and keep their capacity.
+func NewBufferPool(size int) *BufferPool {
+	return &BufferPool{
+		size: size,
+		pool: sync.Pool{New: func() any {
+			buf := make([]byte, size)
+			return &buf
+		}},
+	}
+}
+
+// NewGrowingBufferPool returns a pool of buffers of up to size bytes, which
+// start empty and are grown by ReadChunk as data is read into them. It is
+// meant for sizes read from a file that are not authenticated.
+func NewGrowingBufferPool(size int) *BufferPool {
+	return &BufferPool{
+		size: size,
+		pool: sync.Pool{New: func() any {
+			buf := []byte{}
+			return &buf
+		}},
+	}
+}
+
+func (p *BufferPool) Size() int {
+	return p.size
+}
+
+// Get returns a buffer of Size bytes, or a shorter one that ReadChunk grows
+// to Size bytes.
+func (p *BufferPool) Get() *[]byte {
+	buf := p.pool.Get().(*[]byte)
+	*buf = (*buf)[:min(p.size, cap(*buf))]
+	return buf
+}
+
+func (p *BufferPool) Put(buf *[]byte) {
+	p.pool.Put(buf)
+}
@@ -1,53 +1,78 @@
 package file
 
 import (
-	"errors"
-	"fmt"
+	"bufio"
+	"context"
 	"io"
-	"os"
+	"slices"
 )
 
-func ReadDecryptedFile(path string, blockSize int) (<-chan []byte, <-chan error, error) {
-	f, err := os.OpenFile(path, os.O_RDONLY, 0644)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil, nil, fmt.Errorf("file not found")
-		}
-		return nil, nil, err
+type Content struct {
+	Index uint64
+	Last  bool
+	Nonce []byte
+	Buf   []byte
+
+	pool *BufferPool
+	buf  *[]byte
+}
+
+// Release returns the buffer of the chunk to its pool. Nonce and Buf must
+// not be used after it.
+func (c Content) Release() {
+	if c.pool != nil {
+		c.pool.Put(c.buf)
 	}
+}
 
-	outCh := make(chan []byte)
+// ReadDecrypted splits r into chunks of pool.Size() bytes. Every chunk holds
+// a buffer from pool until it is released. Reading stops and both channels
+// are closed when ctx is done.
+func ReadDecrypted(ctx context.Context, rd io.Reader, pool *BufferPool) (<-chan Content, <-chan error) {
+	outCh := make(chan Content)
 	errCh := make(chan error)
 
 	go func() {
-		defer f.Close()
 		defer close(outCh)
 		defer close(errCh)
 
-		buf := make([]byte, blockSize)
+		r := bufio.NewReader(rd)
+		for index := uint64(0); ; index++ {
+			buf := pool.Get()
 
-		for {
-			n, err := f.Read(buf)
+			n, last, err := ReadChunk(r, buf, pool.Size())
 			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				errCh <- err
+				pool.Put(buf)
+				send(ctx, errCh, err)
+				return
 			}
 
-			outCh <- buf[:n]
+			c := Content{
+				Index: index,
+				Last:  last,
+				Buf:   (*buf)[:n],
+				pool:  pool,
+				buf:   buf,
+			}
+			if !send(ctx, outCh, c) {
+				c.Release()
+				return
+			}
+
+			if last {
+				return
+			}
 		}
 	}()
 
-	return outCh, errCh, nil
-}
-
-type Content struct {
-	Nonce []byte
-	Buf   []byte
+	return outCh, errCh
 }
 
-func ReadEncryptedFile(f *os.File, nonceSize, blockSize, tagSize int) (<-chan Content, <-chan error, error) {
+// ReadEncryptedFile splits the chunk stream into chunks of pool.Size() bytes, the
+// size of a sealed chunk. nonceSize is the size of the nonce stored in front of every
+// chunk, it is zero when nonces are derived from the chunk index. Every chunk holds a
+// buffer from pool until it is released. Reading stops when ctx is done.
+func ReadEncryptedFile(ctx context.Context, f io.Reader, nonceSize int, pool *BufferPool) (<-chan Content, <-chan error, error) {
 	outCh := make(chan Content)
 	errCh := make(chan error)
 
@@ -55,31 +80,88 @@ func ReadEncryptedFile(f *os.File, nonceSize, blockSize, tagSize int) (<-chan Co
 		defer close(outCh)
 		defer close(errCh)
 
-		nonce := make([]byte, nonceSize)
-		buf := make([]byte, blockSize+tagSize)
-		for {
-			n, err := f.Read(nonce)
+		r := bufio.NewReader(f)
+		for index := uint64(0); ; index++ {
+			buf := pool.Get()
+
+			n, last, err := ReadChunk(r, buf, pool.Size())
 			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				errCh <- err
+				pool.Put(buf)
+				send(ctx, errCh, err)
+				return
 			}
+			chunk := (*buf)[:n]
 
-			m, err := f.Read(buf)
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				errCh <- err
+			if n < nonceSize {
+				nonceSize = n
+			}
+
+			c := Content{
+				Index: index,
+				Last:  last,
+				Nonce: chunk[:nonceSize],
+				Buf:   chunk[nonceSize:],
+				pool:  pool,
+				buf:   buf,
+			}
+			if !send(ctx, outCh, c) {
+				c.Release()
+				return
 			}
 
-			outCh <- Content{
-				Nonce: nonce[:n],
-				Buf:   buf[:m],
+			if last {
+				return
 			}
 		}
 	}()
 
 	return outCh, errCh, nil
 }
+
+// send reports false if ctx is done before v is received.
+func send[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// ReadChunk reads a chunk of size bytes into buf and reports whether it was
+// the last chunk of the stream. A full chunk is the last one only if nothing
+// follows it.
+func ReadChunk(r *bufio.Reader, buf *[]byte, size int) (int, bool, error) {
+	n, err := readFull(r, buf, size)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return n, true, nil
+		}
+		return n, false, err
+	}
+
+	if _, err := r.Peek(1); err != nil {
+		if err == io.EOF {
+			return n, true, nil
+		}
+		return n, false, err
+	}
+
+	return n, false, nil
+}
+
+// readFull reads size bytes into buf. A shorter buf is grown as data arrives,
+// at most doubling, so a chunk costs no more than twice its actual size.
+func readFull(r io.Reader, buf *[]byte, size int) (int, error) {
+	n := 0
+	for {
+		m, err := io.ReadFull(r, (*buf)[n:])
+		n += m
+		if err != nil || n == size {
+			return n, err
+		}
+
+		grow := min(max(n, 4096), size-n)
+		*buf = slices.Grow(*buf, grow)[:n+grow]
+	}
+}
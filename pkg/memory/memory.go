@@ -2,7 +2,10 @@ package memory
 
 import (
 	"fmt"
+	"math"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/disk"
@@ -104,3 +107,37 @@ func CalculateThroughput(fileSize int64, duration time.Duration) float64 {
 
 	return fileSizeMB / timeSec
 }
+
+// ParseBytes parses a size such as "4096", "64KiB", "1M" or "2GB". Units are
+// powers of 1024, like in FormatBytes.
+func ParseBytes(s string) (int64, error) {
+	units := []struct {
+		suffixes []string
+		size     int64
+	}{
+		{[]string{"gib", "gb", "g"}, 1 << 30},
+		{[]string{"mib", "mb", "m"}, 1 << 20},
+		{[]string{"kib", "kb", "k"}, 1 << 10},
+		{[]string{"b"}, 1},
+	}
+
+	number := strings.ToLower(strings.TrimSpace(s))
+	multiplier := int64(1)
+
+UNITS:
+	for _, unit := range units {
+		for _, suffix := range unit.suffixes {
+			if trimmed, ok := strings.CutSuffix(number, suffix); ok {
+				number, multiplier = strings.TrimSpace(trimmed), unit.size
+				break UNITS
+			}
+		}
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return n * multiplier, nil
+}
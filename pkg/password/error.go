@@ -0,0 +1,17 @@
+package password
+
+import "errors"
+
+var (
+	ErrEmpty              = errors.New("password cannot be empty")
+	ErrTooLong            = errors.New("password is too long")
+	ErrMismatch           = errors.New("passwords do not match")
+	ErrConflictingSources = errors.New("only one of --password, --password-file and --password-fd can be given")
+	ErrNoTerminal         = errors.New("no password given and no terminal to prompt on, use --password-file, --password-fd or " + EnvVar)
+
+	ErrTooWeak      = errors.New("password is too weak, use a stronger one or --allow-weak")
+	ErrInvalidScore = errors.New("invalid password strength")
+
+	ErrInvalidLength  = errors.New("invalid generated password length")
+	ErrInvalidCharset = errors.New("a character set of at least two characters is required")
+)
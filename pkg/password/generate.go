@@ -0,0 +1,115 @@
+package password
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Character sets of GenerateChars.
+const (
+	CharsLower   = "abcdefghijklmnopqrstuvwxyz"
+	CharsUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	CharsDigits  = "0123456789"
+	CharsSymbols = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+)
+
+const (
+	// DefaultWords passphrase words give about 82 bits of entropy
+	DefaultWords     = 8
+	DefaultSeparator = "-"
+	// DefaultLength characters of all sets give about 130 bits of entropy
+	DefaultLength = 20
+
+	maxGenerated = 1024
+)
+
+// wordlist.txt is a diceware list: four dice rolls select one of 6^4 words.
+//
+//go:embed wordlist.txt
+var wordlistFile string
+
+var wordlist = func() []string {
+	var words []string
+	for _, line := range strings.Split(strings.TrimSpace(wordlistFile), "\n") {
+		if _, word, ok := strings.Cut(line, "\t"); ok {
+			words = append(words, word)
+		}
+	}
+	return words
+}()
+
+// Generated is a random password and its entropy.
+type Generated struct {
+	Password []byte
+	// Entropy is in bits
+	Entropy float64
+}
+
+// GeneratePassphrase joins words random words of the embedded wordlist with
+// separator.
+func GeneratePassphrase(words int, separator string) (Generated, error) {
+	if words < 1 || words > maxGenerated {
+		return Generated{}, fmt.Errorf("%w: %d words, expected 1 to %d", ErrInvalidLength, words, maxGenerated)
+	}
+
+	chosen := make([]string, words)
+	for i := range chosen {
+		n, err := randomIndex(len(wordlist))
+		if err != nil {
+			return Generated{}, err
+		}
+		chosen[i] = wordlist[n]
+	}
+
+	return Generated{
+		Password: []byte(strings.Join(chosen, separator)),
+		Entropy:  float64(words) * math.Log2(float64(len(wordlist))),
+	}, nil
+}
+
+// GenerateChars picks length random characters of charset. Repeated
+// characters of charset count once.
+func GenerateChars(length int, charset string) (Generated, error) {
+	if length < 1 || length > maxGenerated {
+		return Generated{}, fmt.Errorf("%w: %d characters, expected 1 to %d", ErrInvalidLength, length, maxGenerated)
+	}
+
+	var chars []rune
+	seen := map[rune]bool{}
+	for _, r := range charset {
+		if !seen[r] {
+			seen[r] = true
+			chars = append(chars, r)
+		}
+	}
+	if len(chars) < 2 {
+		return Generated{}, ErrInvalidCharset
+	}
+
+	var b strings.Builder
+	for range length {
+		n, err := randomIndex(len(chars))
+		if err != nil {
+			return Generated{}, err
+		}
+		b.WriteRune(chars[n])
+	}
+
+	return Generated{
+		Password: []byte(b.String()),
+		Entropy:  float64(length) * math.Log2(float64(len(chars))),
+	}, nil
+}
+
+// randomIndex returns a uniform random number in [0, n).
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("error generating a password: %w", err)
+	}
+	return int(i.Int64()), nil
+}
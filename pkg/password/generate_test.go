@@ -0,0 +1,107 @@
+package password
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWordlist(t *testing.T) {
+	if len(wordlist) != 6*6*6*6 {
+		t.Fatalf("get words: %d, expected words: %d", len(wordlist), 6*6*6*6)
+	}
+
+	seen := map[string]bool{}
+	for _, word := range wordlist {
+		if seen[word] {
+			t.Fatalf("duplicate word: %s", word)
+		}
+		seen[word] = true
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	type Case struct {
+		name      string
+		words     int
+		separator string
+		err       error
+	}
+
+	cases := []Case{
+		{name: "default", words: DefaultWords, separator: DefaultSeparator},
+		{name: "space", words: 4, separator: " "},
+		{name: "one word", words: 1, separator: "-"},
+		{name: "no words", words: 0, separator: "-", err: ErrInvalidLength},
+		{name: "too many words", words: maxGenerated + 1, separator: "-", err: ErrInvalidLength},
+	}
+
+	for _, c := range cases {
+		generated, err := GeneratePassphrase(c.words, c.separator)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+		if c.err != nil {
+			continue
+		}
+
+		words := strings.Split(string(generated.Password), c.separator)
+		if len(words) != c.words {
+			t.Fatalf("[%s] get words: %d, expected words: %d", c.name, len(words), c.words)
+		}
+		if entropy := float64(c.words) * math.Log2(1296); math.Abs(generated.Entropy-entropy) > 1e-9 {
+			t.Fatalf("[%s] get entropy: %v, expected entropy: %v", c.name, generated.Entropy, entropy)
+		}
+	}
+}
+
+func TestGenerateChars(t *testing.T) {
+	type Case struct {
+		name    string
+		length  int
+		charset string
+		entropy float64
+		err     error
+	}
+
+	cases := []Case{
+		{name: "digits", length: 10, charset: CharsDigits, entropy: 10 * math.Log2(10)},
+		{name: "all sets", length: DefaultLength, charset: CharsLower + CharsUpper + CharsDigits + CharsSymbols, entropy: DefaultLength * math.Log2(90)},
+		{name: "repeated chars", length: 8, charset: "abab", entropy: 8},
+		{name: "single char", length: 8, charset: "aaa", err: ErrInvalidCharset},
+		{name: "no length", length: 0, charset: CharsDigits, err: ErrInvalidLength},
+	}
+
+	for _, c := range cases {
+		generated, err := GenerateChars(c.length, c.charset)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+		if c.err != nil {
+			continue
+		}
+
+		if len(generated.Password) != c.length {
+			t.Fatalf("[%s] get length: %d, expected length: %d", c.name, len(generated.Password), c.length)
+		}
+		for _, r := range string(generated.Password) {
+			if !strings.ContainsRune(c.charset, r) {
+				t.Fatalf("[%s] get char: %q, not in charset: %q", c.name, r, c.charset)
+			}
+		}
+		if math.Abs(generated.Entropy-c.entropy) > 1e-9 {
+			t.Fatalf("[%s] get entropy: %v, expected entropy: %v", c.name, generated.Entropy, c.entropy)
+		}
+	}
+}
+
+func TestGeneratedIsStrong(t *testing.T) {
+	generated, err := GeneratePassphrase(DefaultWords, DefaultSeparator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := Estimate(generated.Password); s.Score != ScoreVeryStrong {
+		t.Fatalf("get score: %d, expected score: %d", s.Score, ScoreVeryStrong)
+	}
+}
@@ -0,0 +1,124 @@
+// Package password reads passwords from the command line, files, file
+// descriptors, the environment or an interactive prompt with echo disabled.
+package password
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EnvVar holds the password when no other source is given.
+const EnvVar = "CRYPTOOL_PASSWORD"
+
+// maxSize limits what is read from a password file or descriptor.
+const maxSize = 4096
+
+// Sources are the places a password can come from. At most one of Password,
+// File and FD may be set, without any the environment and then the terminal
+// are tried.
+type Sources struct {
+	// Password is given on the command line
+	Password string
+	// File holds the password on its first line
+	File string
+	// FD is an open file descriptor with the password on its first line,
+	// negative if not set
+	FD int
+}
+
+// Read returns the password from the first available source. The prompt asks
+// twice when confirm is set.
+func Read(src Sources, confirm bool) ([]byte, error) {
+	given := 0
+	for _, set := range []bool{src.Password != "", src.File != "", src.FD >= 0} {
+		if set {
+			given++
+		}
+	}
+	if given > 1 {
+		return nil, ErrConflictingSources
+	}
+
+	switch {
+	case src.Password != "":
+		return []byte(src.Password), nil
+	case src.File != "":
+		return ReadFile(src.File)
+	case src.FD >= 0:
+		return ReadFD(src.FD)
+	}
+
+	if env, ok := os.LookupEnv(EnvVar); ok {
+		if env == "" {
+			return nil, fmt.Errorf("%w: %s is set but empty", ErrEmpty, EnvVar)
+		}
+		return []byte(env), nil
+	}
+
+	if confirm {
+		return PromptConfirm("Enter password: ", "Confirm password: ")
+	}
+	return Prompt("Enter password: ")
+}
+
+// ReadFile returns the first line of the file at path.
+func ReadFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the password file: %w", err)
+	}
+	defer f.Close()
+
+	return readLine(f)
+}
+
+// ReadFD returns the first line read from the file descriptor fd. Nothing
+// after the line is consumed, so fd 0 can carry the password followed by the
+// data.
+func ReadFD(fd int) ([]byte, error) {
+	// a second os.File of stdin would close it when collected
+	if fd == 0 {
+		return readLine(os.Stdin)
+	}
+
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+	if f == nil {
+		return nil, fmt.Errorf("error reading the password: invalid file descriptor %d", fd)
+	}
+
+	return readLine(f)
+}
+
+// readLine reads up to the first newline one byte at a time, a trailing
+// carriage return is dropped.
+func readLine(r io.Reader) ([]byte, error) {
+	line := []byte{}
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			if len(line) == maxSize {
+				return nil, ErrTooLong
+			}
+			line = append(line, b[0])
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading the password: %w", err)
+		}
+	}
+
+	line = []byte(strings.TrimSuffix(string(line), "\r"))
+	if len(line) == 0 {
+		return nil, ErrEmpty
+	}
+	return line, nil
+}
@@ -0,0 +1,102 @@
+package password
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	pipe := func(content string) int {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { r.Close() })
+
+		if _, err := w.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		return int(r.Fd())
+	}
+
+	type Case struct {
+		name     string
+		src      Sources
+		env      string
+		password string
+		err      error
+	}
+
+	cases := []Case{
+		{name: "command line", src: Sources{Password: "secret", FD: -1}, password: "secret"},
+		{name: "file", src: Sources{File: writeFile("file", "secret\nignored\n"), FD: -1}, password: "secret"},
+		{name: "file with crlf", src: Sources{File: writeFile("crlf", "secret\r\n"), FD: -1}, password: "secret"},
+		{name: "file without newline", src: Sources{File: writeFile("bare", "secret"), FD: -1}, password: "secret"},
+		{name: "empty file", src: Sources{File: writeFile("empty", "\n"), FD: -1}, err: ErrEmpty},
+		{name: "long file", src: Sources{File: writeFile("long", string(bytes.Repeat([]byte("a"), maxSize+1))), FD: -1}, err: ErrTooLong},
+		{name: "fd", src: Sources{FD: pipe("secret\n")}, password: "secret"},
+		{name: "env", src: Sources{FD: -1}, env: "secret", password: "secret"},
+		{name: "flag before env", src: Sources{Password: "flag", FD: -1}, env: "secret", password: "flag"},
+		{name: "conflicting sources", src: Sources{Password: "secret", File: writeFile("conflict", "secret"), FD: -1}, err: ErrConflictingSources},
+	}
+
+	// restored after the test
+	t.Setenv(EnvVar, "")
+
+	for _, c := range cases {
+		if c.env != "" {
+			os.Setenv(EnvVar, c.env)
+		} else {
+			os.Unsetenv(EnvVar)
+		}
+
+		password, err := Read(c.src, false)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+		if string(password) != c.password {
+			t.Fatalf("[%s] get password: %q, expected: %q", c.name, password, c.password)
+		}
+	}
+}
+
+func TestReadFDLeavesData(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := w.WriteString("secret\ndata"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	password, err := ReadFD(int(r.Fd()))
+	if err != nil {
+		t.Fatalf("get error: %v, expected error: %v", err, nil)
+	}
+	if string(password) != "secret" {
+		t.Fatalf("get password: %q, expected: %q", password, "secret")
+	}
+
+	rest := make([]byte, 16)
+	n, _ := r.Read(rest)
+	if string(rest[:n]) != "data" {
+		t.Fatalf("get data: %q, expected: %q", rest[:n], "data")
+	}
+}
@@ -0,0 +1,52 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+
+	"golang.org/x/term"
+)
+
+// Prompt asks for a password on the terminal with echo disabled. The
+// terminal is opened directly, so stdin and stdout stay free for data.
+func Prompt(prompt string) ([]byte, error) {
+	tty, err := openTerminal()
+	if err != nil {
+		return nil, ErrNoTerminal
+	}
+	defer tty.Close()
+
+	if !term.IsTerminal(int(tty.in.Fd())) {
+		return nil, ErrNoTerminal
+	}
+
+	fmt.Fprint(tty.out, prompt)
+	password, err := term.ReadPassword(int(tty.in.Fd()))
+	fmt.Fprintln(tty.out)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the password: %w", err)
+	}
+
+	if len(password) == 0 {
+		return nil, ErrEmpty
+	}
+	return password, nil
+}
+
+// PromptConfirm asks for a password twice and fails if the answers differ.
+func PromptConfirm(prompt, confirm string) ([]byte, error) {
+	password, err := Prompt(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	again, err := Prompt(confirm)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(password, again) {
+		return nil, ErrMismatch
+	}
+	return password, nil
+}
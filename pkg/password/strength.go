@@ -0,0 +1,544 @@
+package password
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+)
+
+// Scores of Estimate, in the spirit of zxcvbn.
+const (
+	ScoreVeryWeak = iota
+	ScoreWeak
+	ScoreFair
+	ScoreStrong
+	ScoreVeryStrong
+
+	// DefaultMinScore is the weakest password encrypt accepts by default
+	DefaultMinScore = ScoreFair
+)
+
+var scoreNames = []string{"very weak", "weak", "fair", "strong", "very strong"}
+
+// log10 of the guesses below which a password gets each score
+var scoreThresholds = []float64{3, 6, 8, 10}
+
+const (
+	// passwords are analyzed up to this length, the rest is counted as
+	// random characters
+	maxAnalyzed = 100
+
+	bruteforceCardinality = 10
+	minGuessesSingleChar  = 10
+	minGuessesMultiChar   = 50
+
+	// attacker hash rate, in hash function calls or memory blocks per
+	// second, roughly a rig of a few GPUs
+	attackerRate = 1e10
+)
+
+//go:embed common.txt
+var commonList string
+
+// common maps common passwords and words to their rank.
+var common = func() map[string]int {
+	ranks := map[string]int{}
+	for i, word := range strings.Fields(commonList) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}()
+
+var leet = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g',
+	'1': 'i', '!': 'i', '|': 'l', '0': 'o', '$': 's', '5': 's',
+	'7': 't', '+': 't', '2': 'z',
+}
+
+// Strength is an estimate of how many guesses an attacker needs to find a
+// password.
+type Strength struct {
+	// Log10Guesses is log10 of the estimated number of guesses
+	Log10Guesses float64
+	Score        int
+	// Warning names the weakest pattern found, empty if none stands out
+	Warning string
+}
+
+func (s Strength) String() string {
+	return fmt.Sprintf("%s (%d/%d)", scoreNames[s.Score], s.Score, ScoreVeryStrong)
+}
+
+// CrackSeconds returns the expected time in seconds to find the password
+// when every guess costs a key derivation with params.
+func (s Strength) CrackSeconds(params *crypto.KDFParams) float64 {
+	// on average half of the guesses are needed
+	return math.Pow(10, s.Log10Guesses) / 2 / GuessesPerSecond(params)
+}
+
+// GuessesPerSecond estimates the passwords an attacker tries per second
+// against the key derivation with params.
+func GuessesPerSecond(params *crypto.KDFParams) float64 {
+	var cost float64
+	switch params.KDF {
+	case crypto.KDFScrypt:
+		// N blocks of 128*r bytes are written and read back
+		cost = 2 * float64(params.Memory) * 8
+	case crypto.KDFArgon2id:
+		// every pass fills the memory with 1 KiB blocks
+		cost = float64(params.Time) * float64(params.Memory)
+	default:
+		cost = float64(params.Time)
+	}
+
+	return attackerRate / max(cost, 1)
+}
+
+// FormatSeconds formats a crack time for people.
+func FormatSeconds(seconds float64) string {
+	units := []struct {
+		name    string
+		seconds float64
+	}{
+		{"year", 365 * 24 * 3600},
+		{"month", 30 * 24 * 3600},
+		{"day", 24 * 3600},
+		{"hour", 3600},
+		{"minute", 60},
+		{"second", 1},
+	}
+
+	if seconds < 1 {
+		return "less than a second"
+	}
+	if seconds >= 100*units[0].seconds {
+		return "centuries"
+	}
+
+	for _, unit := range units {
+		if seconds >= unit.seconds {
+			n := int(seconds / unit.seconds)
+			if n == 1 {
+				return "1 " + unit.name
+			}
+			return fmt.Sprintf("%d %ss", n, unit.name)
+		}
+	}
+	return "less than a second"
+}
+
+// match is a part of the password that follows a guessable pattern.
+type match struct {
+	i, j    int
+	log10   float64
+	pattern string
+}
+
+// Estimate finds common passwords, words, keyboard walks, sequences,
+// repeats and dates in password and picks the split into patterns an attacker
+// would guess first, like zxcvbn does.
+func Estimate(password []byte) Strength {
+	runes := []rune(string(password))
+	rest := 0
+	if len(runes) > maxAnalyzed {
+		rest = len(runes) - maxAnalyzed
+		runes = runes[:maxAnalyzed]
+	}
+
+	matches := findMatches(runes)
+	log10, weakest := mostGuessable(runes, matches)
+	log10 += float64(rest) * math.Log10(bruteforceCardinality)
+
+	s := Strength{Log10Guesses: log10}
+	for s.Score < ScoreVeryStrong && log10 >= scoreThresholds[s.Score] {
+		s.Score++
+	}
+
+	if weakest != nil && s.Score < ScoreStrong && 2*(weakest.j-weakest.i+1) >= len(runes) {
+		s.Warning = weakest.pattern
+	}
+	return s
+}
+
+// mostGuessable splits runes into matches and random characters with the
+// lowest number of guesses. A split into l parts costs l! * the product of
+// the guesses of the parts, plus a penalty for every extra part.
+func mostGuessable(runes []rune, matches []match) (float64, *match) {
+	n := len(runes)
+	if n == 0 {
+		return 0, nil
+	}
+
+	byEnd := make([][]match, n)
+	for _, m := range matches {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+	for j := range n {
+		for i := 0; i <= j; i++ {
+			byEnd[j] = append(byEnd[j], bruteforce(i, j))
+		}
+	}
+
+	// best[k][l] is the lowest log10 product of l parts covering runes[:k+1]
+	best := make([][]float64, n)
+	last := make([][]*match, n)
+	for k := range n {
+		best[k] = make([]float64, n+2)
+		last[k] = make([]*match, n+2)
+		for l := range best[k] {
+			best[k][l] = math.Inf(1)
+		}
+	}
+
+	for k := range n {
+		for idx := range byEnd[k] {
+			m := &byEnd[k][idx]
+			if m.i == 0 {
+				if m.log10 < best[k][1] {
+					best[k][1], last[k][1] = m.log10, m
+				}
+				continue
+			}
+			for l := 1; l <= m.i; l++ {
+				if v := best[m.i-1][l] + m.log10; v < best[k][l+1] {
+					best[k][l+1], last[k][l+1] = v, m
+				}
+			}
+		}
+	}
+
+	total, parts := math.Inf(1), 0
+	for l := 1; l <= n; l++ {
+		if math.IsInf(best[n-1][l], 1) {
+			continue
+		}
+		lgamma, _ := math.Lgamma(float64(l + 1))
+		v := log10Sum(best[n-1][l]+lgamma/math.Ln10, float64(l-1)*4)
+		if v < total {
+			total, parts = v, l
+		}
+	}
+
+	// the pattern covering most of the password is reported
+	var weakest *match
+	for k, l := n-1, parts; k >= 0 && l > 0; l-- {
+		m := last[k][l]
+		if m.pattern != "" && (weakest == nil || m.j-m.i > weakest.j-weakest.i) {
+			weakest = m
+		}
+		k = m.i - 1
+	}
+
+	return total, weakest
+}
+
+func bruteforce(i, j int) match {
+	length := j - i + 1
+	log10 := float64(length) * math.Log10(bruteforceCardinality)
+	minimum := minGuessesMultiChar
+	if length == 1 {
+		minimum = minGuessesSingleChar
+	}
+	return match{i: i, j: j, log10: math.Max(log10, math.Log10(float64(minimum)))}
+}
+
+func findMatches(runes []rune) []match {
+	matches := []match{}
+	matches = append(matches, dictionaryMatches(runes)...)
+	matches = append(matches, repeatMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, keyboardMatches(runes)...)
+	matches = append(matches, dateMatches(runes)...)
+	return matches
+}
+
+func dictionaryMatches(runes []rune) []match {
+	lower := []rune(strings.ToLower(string(runes)))
+	unleet := make([]rune, len(lower))
+	for i, r := range lower {
+		if sub, ok := leet[r]; ok {
+			unleet[i] = sub
+		} else {
+			unleet[i] = r
+		}
+	}
+
+	matches := []match{}
+	for i := range runes {
+		for j := i; j < len(runes); j++ {
+			word := string(lower[i : j+1])
+			subbed := string(unleet[i : j+1])
+
+			rank, factor := 0, 1.0
+			switch {
+			case common[word] != 0:
+				rank = common[word]
+			case common[subbed] != 0:
+				rank, factor = common[subbed], 2
+			case common[reverse(word)] != 0:
+				rank, factor = common[reverse(word)], 2
+			default:
+				continue
+			}
+
+			factor *= upperVariations(runes[i : j+1])
+			matches = append(matches, match{
+				i: i, j: j,
+				log10:   math.Log10(float64(rank) * factor),
+				pattern: "a common password or word",
+			})
+		}
+	}
+	return matches
+}
+
+// upperVariations counts the ways to capitalize a word, a first or all
+// capital letters are guessed first.
+func upperVariations(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+
+	if upper == 0 || lower == 0 || (upper == 1 && unicode.IsUpper(word[0])) {
+		if upper == 0 {
+			return 1
+		}
+		return 2
+	}
+
+	variations := 0.0
+	for k := 1; k <= min(upper, lower); k++ {
+		variations += binomial(upper+lower, k)
+	}
+	return variations
+}
+
+func repeatMatches(runes []rune) []match {
+	units := map[string]float64{}
+
+	matches := []match{}
+	for i := range runes {
+		for size := 1; i+2*size <= len(runes); size++ {
+			// only the longest run of a unit is matched
+			if i >= size && string(runes[i-size:i]) == string(runes[i:i+size]) {
+				continue
+			}
+
+			count := 1
+			for i+(count+1)*size <= len(runes) && string(runes[i:i+size]) == string(runes[i+count*size:i+(count+1)*size]) {
+				count++
+			}
+			if count < 2 || (size == 1 && count < 3) {
+				continue
+			}
+
+			unit, ok := units[string(runes[i:i+size])]
+			if !ok {
+				unit, _ = mostGuessable(runes[i:i+size], findMatches(runes[i:i+size]))
+				units[string(runes[i:i+size])] = unit
+			}
+			matches = append(matches, match{
+				i: i, j: i + count*size - 1,
+				log10:   unit + math.Log10(float64(count)),
+				pattern: "a repeated pattern",
+			})
+		}
+	}
+	return matches
+}
+
+func sequenceMatches(runes []rune) []match {
+	matches := []match{}
+	for i := 0; i+2 < len(runes); i++ {
+		delta := runes[i+1] - runes[i]
+		if delta == 0 || delta > 2 || delta < -2 {
+			continue
+		}
+
+		j := i + 1
+		for j+1 < len(runes) && runes[j+1]-runes[j] == delta {
+			j++
+		}
+		if j-i < 2 {
+			continue
+		}
+
+		base := 26.0
+		switch first := unicode.ToLower(runes[i]); {
+		case strings.ContainsRune("az019", first):
+			base = 4
+		case unicode.IsDigit(first):
+			base = 10
+		}
+		if delta < 0 {
+			base *= 2
+		}
+
+		matches = append(matches, match{
+			i: i, j: j,
+			log10:   math.Log10(base * float64(j-i+1)),
+			pattern: "a sequence like abc or 6543",
+		})
+		i = j - 1
+	}
+	return matches
+}
+
+var keyboardRows = []string{"1234567890-=", "qwertyuiop[]", "asdfghjkl;'", "zxcvbnm,./"}
+
+// keyPosition returns the row and column of r on a qwerty keyboard. Rows
+// are shifted by half a key each.
+func keyPosition(r rune) (int, int, bool) {
+	r = unicode.ToLower(r)
+	for row, keys := range keyboardRows {
+		if col := strings.IndexRune(keys, r); col >= 0 {
+			return row, 2*col + row, true
+		}
+	}
+	return 0, 0, false
+}
+
+func keyboardMatches(runes []rune) []match {
+	type step struct{ dr, dc int }
+
+	matches := []match{}
+	for i := 0; i+2 < len(runes); i++ {
+		turns := 0
+		var prev step
+
+		j := i
+		for j+1 < len(runes) {
+			r1, c1, ok1 := keyPosition(runes[j])
+			r2, c2, ok2 := keyPosition(runes[j+1])
+			if !ok1 || !ok2 {
+				break
+			}
+			s := step{r2 - r1, c2 - c1}
+			adjacent := (s.dr == 0 && (s.dc == 2 || s.dc == -2)) ||
+				((s.dr == 1 || s.dr == -1) && s.dc >= -1 && s.dc <= 1)
+			if !adjacent {
+				break
+			}
+			if j == i || s != prev {
+				turns++
+			}
+			prev = s
+			j++
+		}
+		if j-i < 2 {
+			continue
+		}
+
+		// starting key, length and where the walk turns
+		log10 := math.Log10(47*float64(j-i+1)) + float64(turns)*math.Log10(4)
+		matches = append(matches, match{
+			i: i, j: j,
+			log10:   log10,
+			pattern: "a keyboard pattern like qwerty",
+		})
+		i = j - 1
+	}
+	return matches
+}
+
+func dateMatches(runes []rune) []match {
+	matches := []match{}
+	for i := range runes {
+		for _, size := range []int{4, 6, 8} {
+			if i+size > len(runes) {
+				continue
+			}
+			digits := string(runes[i : i+size])
+			if strings.IndexFunc(digits, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0 {
+				continue
+			}
+
+			var log10 float64
+			switch {
+			case size == 4 && isYear(digits):
+				log10 = math.Log10(120)
+			case size == 6 && isDate(digits[:2], digits[2:4]):
+				log10 = math.Log10(365 * 100)
+			case size == 8 && (isDate(digits[:2], digits[2:4]) && isYear(digits[4:]) ||
+				isYear(digits[:4]) && isDate(digits[6:], digits[4:6])):
+				log10 = math.Log10(365 * 120)
+			default:
+				continue
+			}
+
+			matches = append(matches, match{i: i, j: i + size - 1, log10: log10, pattern: "a date or a year"})
+		}
+	}
+	return matches
+}
+
+func isYear(s string) bool {
+	return s >= "1900" && s <= "2039"
+}
+
+// isDate reports whether a and b are a day and a month in either order.
+func isDate(a, b string) bool {
+	day := func(s string) bool { return s >= "01" && s <= "31" }
+	month := func(s string) bool { return s >= "01" && s <= "12" }
+	return day(a) && month(b) || month(a) && day(b)
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// log10Sum returns log10(10^a + 10^b).
+func log10Sum(a, b float64) float64 {
+	hi, lo := math.Max(a, b), math.Min(a, b)
+	return hi + math.Log10(1+math.Pow(10, lo-hi))
+}
+
+// Report describes the strength and the crack time against params.
+func (s Strength) Report(params *crypto.KDFParams) string {
+	report := fmt.Sprintf("strength %s, estimated crack time %s with %s", s, FormatSeconds(s.CrackSeconds(params)), params)
+	if s.Warning != "" {
+		report += ", it looks like " + s.Warning
+	}
+	return report
+}
+
+// ValidateScore checks a minimum score.
+func ValidateScore(score int) error {
+	if score < ScoreVeryWeak || score > ScoreVeryStrong {
+		return fmt.Errorf("%w: %d, expected %d to %d", ErrInvalidScore, score, ScoreVeryWeak, ScoreVeryStrong)
+	}
+	return nil
+}
+
+// Check fails with ErrTooWeak if the score of password is below minScore.
+func Check(password []byte, minScore int, params *crypto.KDFParams) error {
+	s := Estimate(password)
+	if s.Score < minScore {
+		return fmt.Errorf("%w: %s", ErrTooWeak, s.Report(params))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DimaKropachev/cryptool/pkg/crypto"
+)
+
+func TestEstimate(t *testing.T) {
+	type Case struct {
+		name     string
+		password string
+		minScore int
+		maxScore int
+		warning  bool
+	}
+
+	cases := []Case{
+		{name: "single char", password: "1", maxScore: ScoreVeryWeak},
+		{name: "common password", password: "password", maxScore: ScoreVeryWeak, warning: true},
+		{name: "leet common password", password: "P@ssw0rd", maxScore: ScoreVeryWeak, warning: true},
+		{name: "keyboard walk", password: "qwertyuiop", maxScore: ScoreWeak, warning: true},
+		{name: "repeat", password: "aaaaaaaaaaaa", maxScore: ScoreVeryWeak, warning: true},
+		{name: "repeated word", password: "iloveyouiloveyou", maxScore: ScoreWeak, warning: true},
+		{name: "sequence", password: "abcdef123456", maxScore: ScoreWeak, warning: true},
+		{name: "date", password: "01011990", maxScore: ScoreWeak, warning: true},
+		{name: "word and year", password: "dragon2024", maxScore: ScoreWeak, warning: true},
+		{name: "random", password: "x7#Kp2!q", minScore: ScoreFair},
+		{name: "long random", password: "vT9#qL2!mZ8&wR4x", minScore: ScoreVeryStrong, maxScore: ScoreVeryStrong},
+		{name: "passphrase", password: "correct horse battery staple", minScore: ScoreStrong},
+	}
+
+	for _, c := range cases {
+		if c.maxScore == 0 && c.minScore > 0 {
+			c.maxScore = ScoreVeryStrong
+		}
+
+		s := Estimate([]byte(c.password))
+		if s.Score < c.minScore || s.Score > c.maxScore {
+			t.Fatalf("[%s] get score: %d, expected score: %d to %d", c.name, s.Score, c.minScore, c.maxScore)
+		}
+		if (s.Warning != "") != c.warning {
+			t.Fatalf("[%s] get warning: %q, expected warning: %v", c.name, s.Warning, c.warning)
+		}
+	}
+}
+
+func TestEstimateLong(t *testing.T) {
+	password := []byte(strings.Repeat("a1b2c3d4", 512))
+
+	start := time.Now()
+	s := Estimate(password)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("estimate of %d bytes took %v", len(password), elapsed)
+	}
+	if s.Score != ScoreVeryStrong {
+		t.Fatalf("get score: %d, expected score: %d", s.Score, ScoreVeryStrong)
+	}
+}
+
+func TestCrackSeconds(t *testing.T) {
+	s := Strength{Log10Guesses: 10}
+
+	pbkdf2 := s.CrackSeconds(crypto.DefaultKDFParams(crypto.KDFPBKDF2))
+	argon2 := s.CrackSeconds(crypto.DefaultKDFParams(crypto.KDFArgon2id))
+	if argon2 <= pbkdf2 {
+		t.Fatalf("argon2id crack time %v is not above pbkdf2 %v", argon2, pbkdf2)
+	}
+
+	weaker := Strength{Log10Guesses: 5}.CrackSeconds(crypto.DefaultKDFParams(crypto.KDFPBKDF2))
+	if weaker >= pbkdf2 {
+		t.Fatalf("crack time %v of fewer guesses is not below %v", weaker, pbkdf2)
+	}
+}
+
+func TestFormatSeconds(t *testing.T) {
+	type Case struct {
+		seconds float64
+		result  string
+	}
+
+	cases := []Case{
+		{seconds: 0.5, result: "less than a second"},
+		{seconds: 1, result: "1 second"},
+		{seconds: 150, result: "2 minutes"},
+		{seconds: 3 * 3600, result: "3 hours"},
+		{seconds: 40 * 24 * 3600, result: "1 month"},
+		{seconds: 1e12, result: "centuries"},
+	}
+
+	for _, c := range cases {
+		if result := FormatSeconds(c.seconds); result != c.result {
+			t.Fatalf("[%v] get result: %q, expected result: %q", c.seconds, result, c.result)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	type Case struct {
+		name     string
+		password string
+		minScore int
+		err      error
+	}
+
+	cases := []Case{
+		{name: "weak below default", password: "password1", minScore: DefaultMinScore, err: ErrTooWeak},
+		{name: "weak with no minimum", password: "password1", minScore: ScoreVeryWeak},
+		{name: "strong", password: "vT9#qL2!mZ8&wR4x", minScore: ScoreVeryStrong},
+		{name: "fair below very strong", password: "x7#Kp2!q", minScore: ScoreVeryStrong, err: ErrTooWeak},
+	}
+
+	params := crypto.DefaultKDFParams(crypto.KDFPBKDF2)
+	for _, c := range cases {
+		err := Check([]byte(c.password), c.minScore, params)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("[%s] get error: %v, expected error: %v", c.name, err, c.err)
+		}
+	}
+
+	for _, score := range []int{-1, ScoreVeryStrong + 1} {
+		if err := ValidateScore(score); !errors.Is(err, ErrInvalidScore) {
+			t.Fatalf("[%d] get error: %v, expected error: %v", score, err, ErrInvalidScore)
+		}
+	}
+}
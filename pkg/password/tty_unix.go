@@ -0,0 +1,22 @@
+//go:build !windows
+
+package password
+
+import "os"
+
+type terminal struct {
+	in  *os.File
+	out *os.File
+}
+
+func openTerminal() (*terminal, error) {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &terminal{in: f, out: f}, nil
+}
+
+func (t *terminal) Close() error {
+	return t.in.Close()
+}
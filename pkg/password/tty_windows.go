@@ -0,0 +1,30 @@
+//go:build windows
+
+package password
+
+import "os"
+
+type terminal struct {
+	in  *os.File
+	out *os.File
+}
+
+func openTerminal() (*terminal, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+
+	return &terminal{in: in, out: out}, nil
+}
+
+func (t *terminal) Close() error {
+	t.out.Close()
+	return t.in.Close()
+}
@@ -7,6 +7,7 @@ const (
 
 	PrefixEncrypt = "Encrypting"
 	PrefixDecrypt = "Decrypting"
+	PrefixVerify  = "Verifying"
 )
 
 type ProgressBar struct {
@@ -25,15 +26,26 @@ func New(prefix string, total int64) *ProgressBar {
 	}
 }
 
+// Start, Finish and Add do nothing on a nil ProgressBar, streams of unknown
+// length are processed without one.
 func (p *ProgressBar) Start() {
+	if p == nil {
+		return
+	}
 	p.pb.Start()
 }
 
 func (p *ProgressBar) Finish() {
+	if p == nil {
+		return
+	}
 	p.pb.Finish()
 }
 
 func (p *ProgressBar) Add(n int) {
+	if p == nil {
+		return
+	}
 	p.pb.Add(n)
 }
 